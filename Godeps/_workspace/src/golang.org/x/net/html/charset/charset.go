@@ -17,9 +17,9 @@ import (
 	"unicode/utf8"
 
 	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/net/html"
-	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/charmap"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/transform"
 )
 
 // Lookup returns the encoding with the specified label, and its canonical
@@ -52,9 +52,9 @@ func main() {
 	fmt.Println()
 
 	fmt.Println("import (")
-	fmt.Println(`"golang.org/x/text/encoding"`)
+	fmt.Println(`"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding"`)
 	for _, pkg := range []string{"charmap", "japanese", "korean", "simplifiedchinese", "traditionalchinese", "unicode"} {
-		fmt.Printf("\"golang.org/x/text/encoding/%s\"\n", pkg)
+		fmt.Printf("\"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/%s\"\n", pkg)
 	}
 	fmt.Println(")")
 	fmt.Println()
@@ -3,13 +3,13 @@
 package charset
 
 import (
-	"golang.org/x/text/encoding"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/encoding/korean"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/encoding/traditionalchinese"
-	"golang.org/x/text/encoding/unicode"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/charmap"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/japanese"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/korean"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/simplifiedchinese"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/traditionalchinese"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/unicode"
 )
 
 var encodings = map[string]struct {
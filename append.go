@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Дописывать новые объявления в существующие файлы (см. --append)
+var append_output bool
+
+// Содержит ли файл уже какие-то данные
+func nonEmptyFile(path string) bool {
+	if path == "-" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0
+}
+
+// Читает объявления из существующего файла, в который будут дописываться результаты.
+// Если файла нет, возвращает пустой список.
+func readExistingItems(path, format string) ([]*Item, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "jsonl":
+		return readJSONLines(f)
+	case "csv":
+		// Файл без строки названий колонок записан с теми же --columns
+		cols, _, err := csv_format.parse()
+		if err != nil {
+			return nil, err
+		}
+		return readCSVColumns(f, cols)
+	}
+	return nil, fmt.Errorf("Дописывание в формат %s не поддерживается (допустимо: csv, jsonl)", format)
+}
+
+// Открывает файл для дописывания: пропускает объявления, которые в нём уже есть
+func openAppend(path, format string) (ItemWriter, error) {
+	if format == "" {
+		format = formatByPath(path)
+	}
+	if path == "-" {
+		return NewItemWriter(path, format)
+	}
+
+	items, err := readExistingItems(path, format)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewItemWriter(path, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > 0 {
+		Info("В файле %s уже есть объявлений: %d, дописываем только новые", path, len(items))
+	}
+	return NewAppendWriter(w, items), nil
+}
+
+// AppendWriter пропускает объявления, ID или ссылка которых уже есть в файле.
+// Записи этого запуска не учитываются: у объявления с несколькими
+// телефонами на каждый номер своя запись (см. emitPhones).
+type AppendWriter struct {
+	w ItemWriter
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	skipped int
+}
+
+func NewAppendWriter(w ItemWriter, existing []*Item) *AppendWriter {
+	a := &AppendWriter{w: w, seen: make(map[string]bool)}
+	for _, item := range existing {
+		if item.id != "" {
+			a.seen["id:"+item.id] = true
+		}
+		if item.url != "" {
+			a.seen["url:"+item.url] = true
+		}
+	}
+	return a
+}
+
+// Есть ли объявление в файле. Набор seen не меняется после создания,
+// поэтому блокировка не нужна
+func (a *AppendWriter) known(item *Item) bool {
+	return a.seen["id:"+item.id] || a.seen["url:"+item.url]
+}
+
+func (a *AppendWriter) Write(item *Item) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.known(item) {
+		a.skipped++
+		return nil
+	}
+	return a.w.Write(item)
+}
+
+func (a *AppendWriter) Close() error {
+	if a.skipped > 0 {
+		Info("Пропущено записей об объявлениях, которые уже были в файле: %d", a.skipped)
+	}
+	return a.w.Close()
+}
+
+// Отбрасывает объявления, которые уже есть во всех файлах для дописывания,
+// ещё до загрузки объявления и запроса телефона
+type knownRule struct {
+	writers []*AppendWriter
+}
+
+func (r *knownRule) Name() string  { return "known" }
+func (r *knownRule) Listing() bool { return true }
+
+func (r *knownRule) Match(item *Item) bool {
+	for _, w := range r.writers {
+		if !w.known(item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Запоминает записанные объявления
+type memoryWriter struct {
+	items []*Item
+}
+
+func (m *memoryWriter) Write(item *Item) error {
+	m.items = append(m.items, item)
+	return nil
+}
+
+func (m *memoryWriter) Close() error {
+	return nil
+}
+
+func TestAppendWriter(t *testing.T) {
+	existing := []*Item{
+		{id: "1", url: "https://m.avito.ru/moskva/mebel/kreslo_1", phone: "p1"},
+		{url: "https://m.avito.ru/moskva/mebel/stol"},
+	}
+	m := &memoryWriter{}
+	a := NewAppendWriter(m, existing)
+	for _, item := range []*Item{
+		{id: "1", url: "https://m.avito.ru/moskva/mebel/kreslo_1", phone: "p1"}, // уже в файле
+		{id: "2", url: "https://m.avito.ru/moskva/mebel/stol"},                  // та же ссылка
+		{id: "3", url: "https://m.avito.ru/moskva/mebel/divan_3", phone: "p1"},
+		{id: "3", url: "https://m.avito.ru/moskva/mebel/divan_3", phone: "p2"}, // второй телефон
+	} {
+		if err := a.Write(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var phones []string
+	for _, item := range m.items {
+		phones = append(phones, item.id+":"+item.phone)
+	}
+	if want := []string{"3:p1", "3:p2"}; !reflect.DeepEqual(phones, want) {
+		t.Errorf("записано %q, ожидалось %q", phones, want)
+	}
+	if a.skipped != 2 {
+		t.Errorf("пропущено %d, ожидалось 2", a.skipped)
+	}
+}
+
+// Объявления из файлов отбрасываются на странице поиска, если они есть во всех файлах
+func TestKnownRule(t *testing.T) {
+	csv := NewAppendWriter(&memoryWriter{}, []*Item{{id: "1"}, {id: "2"}})
+	jsonl := NewAppendWriter(&memoryWriter{}, []*Item{{id: "1"}, {url: "https://m.avito.ru/moskva/mebel/stol_3"}})
+	f := NewFilter(&knownRule{[]*AppendWriter{csv, jsonl}})
+
+	for _, c := range []struct {
+		item  *Item
+		match bool
+	}{
+		{&Item{id: "1"}, false},
+		{&Item{id: "2"}, true}, // нет в jsonl
+		{&Item{id: "3", url: "https://m.avito.ru/moskva/mebel/stol_3"}, true},
+		{&Item{id: "4"}, true},
+	} {
+		if got := f.MatchListing(c.item); got != c.match {
+			t.Errorf("MatchListing(%s) = %v, ожидалось %v", c.item.id, got, c.match)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+var Blocked error = errors.New("Сайт показал капчу или ограничил доступ")
+
+var blocked_phrases = []string{
+	"доступ ограничен",
+	"доступ временно ограничен",
+	"подтвердите, что вы не робот",
+}
+
+// Страница с капчей или сообщением об ограничении доступа
+func blockedPage(doc *goquery.Document) bool {
+	if doc.Find("form[action*='captcha'], input[name='captcha'], img[src*='captcha']").Length() > 0 {
+		return true
+	}
+	return blockedText(doc.Find("title, h1, h2").Text())
+}
+
+func blockedText(text string) bool {
+	text = strings.ToLower(collapseSpaces(text))
+	for _, phrase := range blocked_phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ответ на запрос телефона, который не удалось разобрать как JSON:
+// страница с капчей или сообщением об ограничении доступа
+func blockedBody(body []byte) bool {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return blockedPage(doc) || blockedText(doc.Text())
+}
+
+// Разблокировка доступа пользователем. Пока один запрос ждёт пользователя,
+// остальные запросы, получившие капчу, ждут его же, а после разблокировки
+// повторяются без нового вопроса (generation меняется при каждой разблокировке).
+var unblock struct {
+	sync.Mutex
+	generation int
+	stdin      *bufio.Reader
+	closed     bool
+}
+
+func unblockGeneration() int {
+	unblock.Lock()
+	defer unblock.Unlock()
+	return unblock.generation
+}
+
+// Ждёт, пока пользователь пройдёт проверку в браузере и вставит cookies.
+// Возвращает true, если запрос к page_url нужно повторить.
+// Без --interactive (или после конца ввода) обход прерывается.
+func waitUnblock(page_url string, generation int) bool {
+	if !options.interactive {
+		return false
+	}
+
+	unblock.Lock()
+	defer unblock.Unlock()
+	if unblock.generation != generation {
+		return true
+	}
+	if unblock.closed {
+		return false
+	}
+
+	Error("Сайт показал капчу или ограничил доступ. Обход приостановлен.")
+	Info("Откройте в браузере %s и пройдите проверку.", page_url)
+	Info("Затем вставьте сюда значение заголовка Cookie из браузера и нажмите Enter (или просто Enter, чтобы повторить запрос):")
+
+	if unblock.stdin == nil {
+		unblock.stdin = bufio.NewReader(os.Stdin)
+	}
+	line, err := unblock.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		unblock.closed = true
+		return false
+	}
+
+	if line = strings.TrimSpace(line); line != "" {
+		u, err := url.Parse(page_url)
+		jar, ok := client.Jar.(*CookieJar)
+		if err == nil && ok {
+			Info("Загружено cookies: %d", jar.ImportHeader(u, line))
+		}
+	}
+	Info("Продолжаем с %s", page_url)
+	unblock.generation++
+	return true
+}
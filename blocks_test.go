@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"strings"
+	"testing"
+)
+
+func TestBlockedPage(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		html    string
+		blocked bool
+	}{
+		{"форма капчи", `<html><body><form action="/captcha"><img src="/captcha/image"><input name="captcha"></form></body></html>`, true},
+		{"доступ ограничен", `<html><head><title>Доступ временно ограничен</title></head><body></body></html>`, true},
+		{"не робот", `<html><body><h2>Подтвердите, что вы   не робот</h2></body></html>`, true},
+		{"captcha в заголовке объявления", `<html><head><title>Решение captcha за деньги</title></head><body><h1>Captcha-сервис</h1></body></html>`, false},
+		{"обычная страница", `<html><body><h1 class="nav-helper-header">Мебель и интерьер</h1></body></html>`, false},
+	} {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(c.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := blockedPage(doc); got != c.blocked {
+			t.Errorf("%s: blockedPage = %v, ожидалось %v", c.name, got, c.blocked)
+		}
+	}
+}
+
+func TestBlockedBody(t *testing.T) {
+	for _, c := range []struct {
+		body    string
+		blocked bool
+	}{
+		{`<html><body><form action="/captcha"><input name="captcha"></form></body></html>`, true},
+		{`<p>Доступ ограничен: проблема с IP</p>`, true},
+		{`Internal Server Error: captcha module failed`, false},
+		{`{"error": "not found"`, false},
+	} {
+		if got := blockedBody([]byte(c.body)); got != c.blocked {
+			t.Errorf("blockedBody(%q) = %v, ожидалось %v", c.body, got, c.blocked)
+		}
+	}
+}
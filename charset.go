@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/net/html/charset"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/encoding/charmap"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/golang.org/x/text/transform"
+	"io"
+	"unicode/utf8"
+)
+
+// Определяет кодировку по BOM, заголовку Content-Type и meta тегам
+// в начале страницы. Если кодировка нигде не указана, текст без
+// символов вне ASCII и корректный UTF-8 считаются UTF-8, а остальное -
+// windows-1251 (charset по умолчанию предполагает windows-1252).
+func detectEncoding(preview []byte, content_type string) (encoding.Encoding, string) {
+	e, name, certain := charset.DetermineEncoding(preview, content_type)
+	if certain || name != "windows-1252" {
+		return e, name
+	}
+	if validUTF8(preview) {
+		return encoding.Nop, "utf-8"
+	}
+	return charmap.Windows1251, "windows-1251"
+}
+
+// Корректный UTF-8 с учётом символа, обрезанного в конце preview
+func validUTF8(preview []byte) bool {
+	for i := len(preview) - 1; i >= 0 && i > len(preview)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(preview[i]) {
+			if !utf8.FullRune(preview[i:]) {
+				preview = preview[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(preview)
+}
+
+// Перекодирует тело ответа в UTF-8
+func decodeBody(body io.Reader, content_type string) (io.Reader, error) {
+	r := bufio.NewReaderSize(body, 1024)
+	preview, err := r.Peek(1024)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	e, name := detectEncoding(preview, content_type)
+	if name == "utf-8" {
+		return r, nil
+	}
+	Debug("Decoding response from %s", name)
+	return transform.NewReader(r, e.NewDecoder()), nil
+}
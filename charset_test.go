@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecodeBody(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		file         string
+		content_type string
+		encoding     string
+		text         string
+	}{
+		{"cp1251 из Content-Type", "cp1251.html", "text/html; charset=windows-1251", "windows-1251", "Кресло офисное"},
+		{"cp1251 из meta", "cp1251_meta.html", "text/html", "windows-1251", "Мебель и интерьер"},
+		{"cp1251 без указания", "cp1251.html", "text/html", "windows-1251", "Кресло офисное"},
+		{"utf-8 из Content-Type", "utf8.html", "text/html; charset=utf-8", "utf-8", "Ж Мебель и интерьер"},
+		{"utf-8 без указания", "utf8.html", "", "utf-8", "Ж Мебель и интерьер"},
+	} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		preview := data
+		if len(preview) > 1024 {
+			preview = preview[:1024]
+		}
+		if _, name := detectEncoding(preview, c.content_type); name != c.encoding {
+			t.Errorf("%s: кодировка %s, ожидалась %s", c.name, name, c.encoding)
+		}
+
+		f, err := os.Open(filepath.Join("testdata", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := decodeBody(f, c.content_type)
+		if err != nil {
+			f.Close()
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		decoded, err := ioutil.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if !utf8.Valid(decoded) {
+			t.Errorf("%s: результат не в UTF-8", c.name)
+		}
+		if !strings.Contains(string(decoded), c.text) {
+			t.Errorf("%s: в результате нет %q", c.name, c.text)
+		}
+	}
+}
+
+func TestValidUTF8(t *testing.T) {
+	// Символ "Ж" (2 байта) разрезан границей в 1024 байта
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "utf8.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview := data[:1024]
+	if utf8.Valid(preview) {
+		t.Fatal("в preview должен быть обрезанный символ")
+	}
+	if !validUTF8(preview) {
+		t.Error("validUTF8: обрезанный в конце символ не должен делать текст некорректным")
+	}
+
+	for _, c := range []struct {
+		text  string
+		valid bool
+	}{
+		{"", true},
+		{"ascii", true},
+		{"Кресло", true},
+		{"Кресло\xd0", true},                // обрезан в конце
+		{"Кр\xd0есло", false},               // обрезан в середине
+		{"\xca\xf0\xe5\xf1\xeb\xee", false}, // "Кресло" в windows-1251
+	} {
+		if got := validUTF8([]byte(c.text)); got != c.valid {
+			t.Errorf("validUTF8(%q) = %v, ожидалось %v", c.text, got, c.valid)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const COLUMN_TIME_LAYOUT = "2006-01-02 15:04"
+
+// Колонка табличного вывода (csv)
+type column struct {
+	name string // имя для --columns
+	ru   string // заголовок на русском
+	en   string // заголовок на английском
+	get  func(item *Item) string
+	set  func(item *Item, value string)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(COLUMN_TIME_LAYOUT)
+}
+
+func parseTime(value string) time.Time {
+	t, _ := time.ParseInLocation(COLUMN_TIME_LAYOUT, value, time.Local)
+	return t
+}
+
+func splitList(value, sep string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, sep)
+}
+
+var columns = []*column{
+	{"id", "ID", "ID",
+		func(item *Item) string { return item.id },
+		func(item *Item, v string) { item.id = v }},
+	{"header", "Заголовок", "Header",
+		func(item *Item) string { return item.header },
+		func(item *Item, v string) { item.header = v }},
+	{"description", "Описание", "Description",
+		func(item *Item) string { return item.description },
+		func(item *Item, v string) { item.description = v }},
+	{"price", "Цена", "Price",
+		func(item *Item) string { return item.price },
+		func(item *Item, v string) { item.price = v }},
+	{"phone", "Телефон", "Phone",
+		func(item *Item) string { return item.phone },
+		func(item *Item, v string) { item.phone = v }},
+	{"location", "Местоположение", "Location",
+		func(item *Item) string { return item.location },
+		func(item *Item, v string) { item.location = v }},
+	{"date", "Дата", "Date",
+		func(item *Item) string { return item.date },
+		func(item *Item, v string) { item.date = v }},
+	{"published", "Опубликовано", "Published",
+		func(item *Item) string { return formatTime(item.published) },
+		func(item *Item, v string) { item.published = parseTime(v) }},
+	{"url", "Ссылка", "URL",
+		func(item *Item) string { return item.url },
+		func(item *Item, v string) { item.url = v }},
+	{"params", "Параметры", "Params",
+		func(item *Item) string { return strings.Join(item.params, "; ") },
+		func(item *Item, v string) { item.params = splitList(v, "; ") }},
+	{"seller", "Продавец", "Seller",
+		func(item *Item) string { return item.seller },
+		func(item *Item, v string) { item.seller = v }},
+	{"seller_type", "Тип продавца", "Seller type",
+		func(item *Item) string { return item.seller_type },
+		func(item *Item, v string) { item.seller_type = v }},
+	{"rating", "Рейтинг", "Rating",
+		func(item *Item) string { return item.rating },
+		func(item *Item, v string) { item.rating = v }},
+	{"views", "Просмотры", "Views",
+		func(item *Item) string { return item.views },
+		func(item *Item, v string) { item.views = v }},
+	{"photos", "Фотографии", "Photos",
+		func(item *Item) string { return strings.Join(item.photos, ";") },
+		func(item *Item, v string) { item.photos = splitList(v, ";") }},
+	{"photo_paths", "Фото", "Photo paths",
+		func(item *Item) string { return strings.Join(item.photo_paths, ";") },
+		func(item *Item, v string) { item.photo_paths = splitList(v, ";") }},
+	{"cluster", "Кластер", "Cluster",
+		func(item *Item) string { return item.cluster },
+		func(item *Item, v string) { item.cluster = v }},
+	{"region", "Регион", "Region",
+		func(item *Item) string { return item.region },
+		func(item *Item, v string) { item.region = v }},
+	{"seen", "Найдено", "Seen",
+		func(item *Item) string { return formatTime(item.seen) },
+		func(item *Item, v string) { item.seen = parseTime(v) }},
+}
+
+func columnByName(name string) *column {
+	for _, c := range columns {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Колонка по заголовку (имени, русскому или английскому названию)
+func columnByTitle(title string) *column {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, c := range columns {
+		if title == c.name || title == strings.ToLower(c.ru) || title == strings.ToLower(c.en) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Разбирает список колонок для --columns
+func parseColumns(names []string) ([]*column, error) {
+	var result []*column
+	for _, name := range names {
+		c := columnByName(strings.TrimSpace(name))
+		if c == nil {
+			var known []string
+			for _, c := range columns {
+				known = append(known, c.name)
+			}
+			return nil, fmt.Errorf("Неизвестная колонка: %s (допустимо: %s)", name, strings.Join(known, ", "))
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// Колонки csv по умолчанию: заголовок, местоположение, телефон, ссылка
+// и, если включены, фото, кластер и регион
+func defaultColumns() []*column {
+	names := []string{"header", "location", "phone", "url"}
+	if photos_dir != "" {
+		names = append(names, "photo_paths")
+	}
+	if dedupe {
+		names = append(names, "cluster")
+	}
+	if multi_region {
+		names = append(names, "region")
+	}
+	result, _ := parseColumns(names)
+	return result
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сохранённая cookie вместе с адресом, с которого она пришла
+type savedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// CookieJar - хранилище cookies, которое можно сохранить на диск
+// и загрузить при следующем запуске, чтобы все запросы выглядели
+// как одна непрерывная сессия.
+type CookieJar struct {
+	jar     *cookiejar.Jar
+	path    string
+	mu      sync.Mutex
+	cookies map[string]savedCookie
+}
+
+func NewCookieJar(path string) (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	j := &CookieJar{jar: jar, path: path, cookies: make(map[string]savedCookie)}
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("Не удалось прочитать cookies из %s: %s", path, err)
+	}
+	now := time.Now()
+	for _, s := range saved {
+		// Запись без cookie (например, "cookie": null после ручной правки файла)
+		if s.Cookie == nil {
+			Debug("Skipping saved cookie without value: %s", s.URL)
+			continue
+		}
+		if !s.Cookie.Expires.IsZero() && s.Cookie.Expires.Before(now) {
+			continue
+		}
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{s.Cookie})
+	}
+	Debug("Loaded %d cookies from %s", len(j.cookies), path)
+	return j, nil
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		key := strings.Join([]string{u.Host, c.Domain, c.Path, c.Name}, ";")
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())) {
+			delete(j.cookies, key)
+			continue
+		}
+		if c.MaxAge > 0 {
+			c.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+		j.cookies[key] = savedCookie{URL: u.Scheme + "://" + u.Host + "/", Cookie: c}
+	}
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Сохраняет cookies на диск
+func (j *CookieJar) Save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	j.mu.Lock()
+	saved := make([]savedCookie, 0, len(j.cookies))
+	for _, s := range j.cookies {
+		saved = append(saved, s)
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+	Debug("Saving %d cookies to %s", len(saved), j.path)
+	return ioutil.WriteFile(j.path, data, 0600)
+}
+
+// Загружает cookies, экспортированные из браузера в формате cookies.txt (Netscape)
+func (j *CookieJar) Import(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		http_only := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			http_only = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			Debug("Skipping malformed cookie line: %s", line)
+			continue
+		}
+
+		domain := fields[0]
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: http_only,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = domain
+		}
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: "/"}
+		j.SetCookies(u, []*http.Cookie{cookie})
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	Info("Загружено cookies из %s: %d", path, count)
+	return nil
+}
+
+// Загружает cookies в формате заголовка Cookie ("name=value; name2=value2"),
+// скопированном из браузера. Cookies ставятся на весь домен сайта.
+func (j *CookieJar) ImportHeader(u *url.URL, header string) int {
+	domain := u.Host
+	if parts := strings.Split(domain, "."); len(parts) > 2 {
+		domain = strings.Join(parts[len(parts)-2:], ".")
+	}
+
+	count := 0
+	for _, part := range strings.Split(header, ";") {
+		name_value := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(name_value) != 2 || name_value[0] == "" {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{{
+			Name:   name_value[0],
+			Value:  name_value[1],
+			Domain: domain,
+			Path:   "/",
+		}})
+		count++
+	}
+	return count
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Записи без cookie в сохранённом файле пропускаются
+func TestNewCookieJarSkipsNil(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selavito")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cookies.json")
+	data := `[
+		{"url": "https://m.avito.ru/", "cookie": null},
+		{"url": "https://m.avito.ru/"},
+		{"url": "https://m.avito.ru/", "cookie": {"Name": "sessid", "Value": "abc", "Path": "/"}}
+	]`
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://m.avito.ru/moskva")
+	cookies := j.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "sessid" || cookies[0].Value != "abc" {
+		t.Errorf("cookies: %v", cookies)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"sync"
+	"time"
+)
+
+// Параметры поиска
+type Search struct {
+	Query    string `json:"query"`
+	Location string `json:"location"`
+	Category string `json:"category"`
+
+	// Максимальное количество объявлений (0 - без ограничения)
+	MaxItems int64 `json:"max_items"`
+
+	// Не обходить объявления старше заданной границы (см. parseSince).
+	// Граница из --max-age тоже останавливает обход, действует более поздняя.
+	Since string `json:"since,omitempty"`
+}
+
+// Обходит страницы результатов поиска и отправляет найденные объявления в items.
+// Обход прекращается досрочно, если закрыт канал stop (может быть nil).
+// Функция progress (может быть nil) вызывается после разбора каждой страницы.
+// Канал items не закрывается - это задача вызывающего кода.
+func crawl(parser Parser, search Search, items chan<- *Item, stop <-chan struct{}, progress func(done int, total string)) error {
+	var since time.Time
+	if search.Since != "" {
+		var err error
+		if since, err = parseSince(search.Since, time.Now()); err != nil {
+			return err
+		}
+	}
+	if max_age := item_filter.Since(); max_age.After(since) {
+		since = max_age
+	}
+	page_url := parser.SearchURL(search.Location, search.Category, search.Query)
+	return crawlPages(page_url, parser.ParsePage, func(item *Item) error {
+		item.region = search.Location
+		return parseItem(parser, item, items)
+	}, search.MaxItems, since, stop, progress)
+}
+
+// Ошибки, после которых обход бессмысленно продолжать:
+// следующие запросы тоже не пройдут
+func fatalError(err error) bool {
+	return err == IPBanned || err == Blocked
+}
+
+// Обходит страницы со списком объявлений начиная с page_url. Каждая страница
+// разбирается функцией parse, а каждое объявление передаётся в отдельной
+// горутине в handle. Если handle вернула ошибку (см. fatalError), обход
+// останавливается и эта ошибка возвращается. max_items == 0 - без ограничения.
+// Объявления отсортированы по дате, поэтому если задан since, объявления
+// старше него пропускаются, а обход прекращается на первой странице,
+// последнее объявление которой старше since (выше могут быть поднятые старые).
+func crawlPages(page_url string, parse func(doc *goquery.Document) (*Page, error), handle func(item *Item) error,
+	max_items int64, since time.Time, stop <-chan struct{}, progress func(done int, total string)) error {
+	var crawl_err error
+	var referer string
+	counter := max_items
+	items_done := 0
+	parse_wg := new(sync.WaitGroup)
+
+	// Первая ошибка, которую вернула handle
+	var fatal struct {
+		sync.Mutex
+		err error
+	}
+	failed := func() bool {
+		fatal.Lock()
+		defer fatal.Unlock()
+		return fatal.err != nil
+	}
+
+	for page_url != "" && (counter > 0 || max_items == 0) && !stopped(stop) && !failed() {
+		Info("Парсинг страницы: %s", page_url)
+
+		throttleWait()
+
+		doc, err := fetchDocument("page", page_url, referer)
+		if err != nil {
+			crawl_err = err
+			break
+		}
+
+		page, err := parse(doc)
+		if err == LayoutChanged {
+			metrics.LayoutError()
+		}
+		if err != nil {
+			crawl_err = err
+			break
+		}
+		if page.next_url != "" {
+			Info("Следующая страница: %s", page.next_url)
+		}
+
+		if items_done == 0 {
+			if page.category != "" {
+				Info("Категория: %s", page.category)
+			}
+			if page.count != "" {
+				Info("Найдено объявлений: %s", page.count)
+			}
+		} else {
+			Info("Процесс выполнения: %d/%s", items_done, page.count)
+		}
+
+		now := time.Now()
+		too_old := false
+		for _, item := range page.items {
+			if (counter <= 0 && max_items != 0) || stopped(stop) || failed() {
+				break
+			}
+			if !since.IsZero() {
+				published, ok := parseDate(item.date, now)
+				if ok {
+					too_old = published.Before(since)
+				}
+				if ok && too_old {
+					Debug("Skipping %s: published %s", item.url, item.date)
+					continue
+				}
+			}
+			if !item_filter.MatchListing(item) {
+				continue
+			}
+			parse_wg.Add(1)
+			go func(item *Item) {
+				defer parse_wg.Done()
+				if err := handle(item); err != nil {
+					fatal.Lock()
+					if fatal.err == nil {
+						fatal.err = err
+					}
+					fatal.Unlock()
+				}
+			}(item)
+			counter--
+			items_done++
+			Debug("%+v\n", *item)
+		}
+		if progress != nil {
+			progress(items_done, page.count)
+		}
+
+		referer = page_url
+		page_url = page.next_url
+		if too_old {
+			Info("Достигнуты объявления старше %s, обход остановлен", since.Format("02.01.2006 15:04"))
+			page_url = ""
+		}
+	}
+
+	// Дожидаемся завершения работы всех парсеров
+	parse_wg.Wait()
+
+	if crawl_err == nil {
+		crawl_err = fatal.err
+	}
+	return crawl_err
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Выполняет поиск и сохраняет найденные объявления через w.
+// Возвращает количество сохранённых объявлений.
+func runSearch(parser Parser, search Search, w ItemWriter, stop <-chan struct{}) (int, error) {
+	return runCrawl(w, func(items chan<- *Item) error {
+		return crawl(parser, search, items, stop, nil)
+	})
+}
+
+// Запускает обход сайта функцией run и сохраняет найденные объявления через w.
+// По завершении w закрывается. Возвращает количество сохранённых объявлений.
+func runCrawl(w ItemWriter, run func(items chan<- *Item) error) (int, error) {
+	items := make(chan *Item)
+	saved := make(chan int)
+	go func() {
+		count := 0
+		for item := range items {
+			if err := w.Write(item); err != nil {
+				Error("Не удалось сохранить объявление: %s", err)
+				itemFailed(&OutputError{err})
+				continue
+			}
+			metrics.ItemEmitted()
+			count++
+		}
+		saved <- count
+	}()
+
+	err := run(items)
+
+	// Закрываем канал только после завершения работы всех парсеров...
+	close(items)
+
+	// ...и ждём пока данные окончательно сохранятся
+	count := <-saved
+	if cerr := w.Close(); cerr != nil && err == nil {
+		err = &OutputError{cerr}
+	}
+	return count, err
+}
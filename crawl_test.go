@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Бан при обработке объявления останавливает обход и возвращается из crawlPages
+func TestCrawlPagesStopsOnBan(t *testing.T) {
+	html, err := ioutil.ReadFile(filepath.Join("testdata", "mobile_search.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	pages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pages++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	}))
+	defer server.Close()
+	initClient(nil)
+
+	const max_pages = 10
+	parse := func(doc *goquery.Document) (*Page, error) {
+		page, err := (&MobileParser{}).ParsePage(doc)
+		if err != nil {
+			return nil, err
+		}
+		// Страницы бесконечны, пока обход не остановится
+		mu.Lock()
+		if pages < max_pages {
+			page.next_url = fmt.Sprintf("%s/?p=%d", server.URL, pages+1)
+		} else {
+			page.next_url = ""
+		}
+		mu.Unlock()
+		return page, nil
+	}
+
+	err = crawlPages(server.URL, parse, func(item *Item) error {
+		return IPBanned
+	}, 0, time.Time{}, nil, nil)
+
+	if err != IPBanned {
+		t.Errorf("crawlPages: %v, ожидалось IPBanned", err)
+	}
+	if pages >= max_pages {
+		t.Errorf("после бана загружено страниц: %d", pages)
+	}
+}
+
+func TestFatalError(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		fatal bool
+	}{
+		{IPBanned, true},
+		{Blocked, true},
+		{LayoutChanged, false},
+		{&NetworkError{fmt.Errorf("timeout")}, false},
+	} {
+		if got := fatalError(c.err); got != c.fatal {
+			t.Errorf("fatalError(%v) = %v, ожидалось %v", c.err, got, c.fatal)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Сохранённый поиск, который запускается по расписанию
+type SavedSearch struct {
+	Name string `json:"name"`
+	Search
+	SiteVariant string `json:"site_variant,omitempty"`
+	Schedule    string `json:"schedule"`
+
+	// Файл для результатов последнего запуска (необязательно,
+	// все результаты в любом случае попадают в --store)
+	Output string `json:"output,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+// Состояние сохранённого поиска после последнего запуска
+type SearchState struct {
+	LastRun    time.Time `json:"last_run"`
+	LastStatus string    `json:"last_status"`
+	LastError  string    `json:"last_error,omitempty"`
+	Items      int       `json:"items"`
+}
+
+// Файл со списком сохранённых поисков (см. --schedules)
+var schedules_path string
+
+func statePath() string {
+	return strings.TrimSuffix(schedules_path, filepath.Ext(schedules_path)) + "_state.json"
+}
+
+func loadSchedules() ([]SavedSearch, error) {
+	var searches []SavedSearch
+	if err := readJSONFile(schedules_path, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+func saveSchedules(searches []SavedSearch) error {
+	return writeJSONFile(schedules_path, searches)
+}
+
+func loadStates() (map[string]SearchState, error) {
+	states := make(map[string]SearchState)
+	if err := readJSONFile(statePath(), &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// Читает JSON файл, отсутствие файла ошибкой не считается
+func readJSONFile(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("Не удалось прочитать %s: %s", path, err)
+	}
+	return nil
+}
+
+// Записывает JSON файл целиком (через временный файл)
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp_path := path + ".tmp"
+	if err := ioutil.WriteFile(tmp_path, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp_path, path)
+}
+
+// Daemon запускает сохранённые поиски по расписанию.
+// Один и тот же поиск не запускается повторно, пока не завершился
+// предыдущий запуск; все поиски используют общую паузу между запросами.
+type Daemon struct {
+	mu      sync.Mutex
+	states  map[string]SearchState
+	running map[string]bool
+	started time.Time
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewDaemon() (*Daemon, error) {
+	states, err := loadStates()
+	if err != nil {
+		return nil, err
+	}
+	return &Daemon{
+		states:  states,
+		running: make(map[string]bool),
+		started: time.Now(),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Проверяет расписания и запускает поиски, которым пора выполняться.
+// Список поисков перечитывается каждый раз, поэтому изменения
+// через "selavito schedule" подхватываются без перезапуска.
+func (d *Daemon) tick(now time.Time) {
+	searches, err := loadSchedules()
+	if err != nil {
+		Error(err.Error())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, s := range searches {
+		schedule, err := ParseSchedule(s.Schedule)
+		if err != nil {
+			Error("%s: %s", s.Name, err)
+			continue
+		}
+		if d.running[s.Name] {
+			continue
+		}
+
+		var next time.Time
+		if last := d.states[s.Name].LastRun; last.IsZero() {
+			next = schedule.First(d.started)
+		} else {
+			next = schedule.Next(last)
+		}
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		d.running[s.Name] = true
+		d.wg.Add(1)
+		go d.run(s, now)
+	}
+}
+
+func (d *Daemon) run(s SavedSearch, started time.Time) {
+	defer d.wg.Done()
+	Info("Запуск поиска по расписанию: %s", s.Name)
+
+	state := SearchState{LastRun: started, LastStatus: "ok"}
+	count, err := d.search(s)
+	state.Items = count
+	if err != nil {
+		state.LastStatus = "error"
+		state.LastError = err.Error()
+		Error("%s: %s", s.Name, err)
+	}
+	Info("Поиск %s завершён, сохранено объявлений: %d", s.Name, count)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running[s.Name] = false
+	d.states[s.Name] = state
+	if err := writeJSONFile(statePath(), d.states); err != nil {
+		Error("Не удалось сохранить состояние: %s", err)
+	}
+}
+
+func (d *Daemon) search(s SavedSearch) (int, error) {
+	parser, err := NewParser(s.SiteVariant)
+	if err != nil {
+		return 0, err
+	}
+
+	var writers multiWriter
+	if s.Output != "" {
+		w, err := NewItemWriter(s.Output, s.Format)
+		if err != nil {
+			return 0, err
+		}
+		writers = append(writers, w)
+	}
+	if options.store != "" {
+		writers = append(writers, OpenStore(options.store))
+	}
+	return runSearch(parser, s.Search, writers, d.stop)
+}
+
+// Работает, пока не получит сигнал завершения, затем дожидается
+// окончания уже запущенных поисков
+func (d *Daemon) Run() {
+	interrupt := make(chan os.Signal, 1)
+	// SIGTERM присылают менеджеры служб (systemd, docker stop)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	d.tick(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			d.tick(now)
+		case <-interrupt:
+			Info("Завершение работы, ожидаем окончания запущенных поисков...")
+			close(d.stop)
+			d.wg.Wait()
+			return
+		}
+	}
+}
+
+var DaemonCmd = &cobra.Command{
+	Use:     "daemon",
+	Short:   "Запускать сохранённые поиски по расписанию",
+	Long:    "Запускает сохранённые поиски (см. \"selavito schedule\") по их расписаниям. Результаты сохраняются в --store.",
+	Example: "selavito daemon -p 2000 --metrics-addr :9090",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		finish, err := startSession()
+		if err != nil {
+			fail(err)
+			return
+		}
+		defer finish()
+
+		d, err := NewDaemon()
+		if err != nil {
+			fail(err)
+			return
+		}
+		Info("Расписания: %s", schedules_path)
+		d.Run()
+	},
+}
+
+var saved_search SavedSearch
+
+var ScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Управление поисками, которые запускаются по расписанию (см. daemon)",
+}
+
+var ScheduleAddCmd = &cobra.Command{
+	Use:   "add <название>",
+	Short: "Добавить или изменить поиск",
+	Long: `Добавляет поиск с заданным расписанием. Форматы расписания:
+  "every 15m"                          каждые 15 минут
+  "every 1h between 08:00 and 23:00"   каждый час с 8 до 23
+  "*/30 9-18 * * 1-5"                  cron: минуты, часы, дни месяца, месяцы, дни недели`,
+	Example: "selavito schedule add kreslo -l moskva -q кресло -m 50 -s \"every 15m between 08:00 and 23:00\"",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 1 || saved_search.Query == "" || saved_search.Schedule == "" {
+			cmd.Help()
+			return
+		}
+		if _, err := ParseSchedule(saved_search.Schedule); err != nil {
+			fail(err)
+			return
+		}
+		if saved_search.Since != "" {
+			if _, err := parseSince(saved_search.Since, time.Now()); err != nil {
+				fail(err)
+				return
+			}
+		}
+		saved_search.SiteVariant = options.site_variant
+		if _, err := NewParser(saved_search.SiteVariant); err != nil {
+			fail(err)
+			return
+		}
+
+		searches, err := loadSchedules()
+		if err != nil {
+			fail(err)
+			return
+		}
+		saved_search.Name = args[0]
+		replaced := false
+		for i := range searches {
+			if searches[i].Name == saved_search.Name {
+				searches[i] = saved_search
+				replaced = true
+			}
+		}
+		if !replaced {
+			searches = append(searches, saved_search)
+		}
+		if err := saveSchedules(searches); err != nil {
+			fail(err)
+			return
+		}
+		Info("Поиск %s сохранён", saved_search.Name)
+	},
+}
+
+var ScheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Список сохранённых поисков",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		searches, err := loadSchedules()
+		if err != nil {
+			fail(err)
+			return
+		}
+		states, err := loadStates()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		names := make([]string, 0, len(searches))
+		by_name := make(map[string]SavedSearch)
+		for _, s := range searches {
+			names = append(names, s.Name)
+			by_name[s.Name] = s
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			s := by_name[name]
+			state := states[name]
+			fmt.Printf("%s\t%q\tq=%s l=%s c=%s m=%d\n", s.Name, s.Schedule, s.Query, s.Location, s.Category, s.MaxItems)
+			if !state.LastRun.IsZero() {
+				fmt.Printf("\tпоследний запуск: %s (%s, объявлений: %d) %s\n",
+					state.LastRun.Format("02.01.2006 15:04"), state.LastStatus, state.Items, state.LastError)
+				if schedule, err := ParseSchedule(s.Schedule); err == nil {
+					fmt.Printf("\tследующий запуск: %s\n", schedule.Next(state.LastRun).Format("02.01.2006 15:04"))
+				}
+			}
+		}
+	},
+}
+
+var ScheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <название>",
+	Short: "Удалить сохранённый поиск",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		searches, err := loadSchedules()
+		if err != nil {
+			fail(err)
+			return
+		}
+		var kept []SavedSearch
+		for _, s := range searches {
+			if s.Name != args[0] {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == len(searches) {
+			fail(fmt.Errorf("Поиск %s не найден", args[0]))
+			return
+		}
+		if err := saveSchedules(kept); err != nil {
+			fail(err)
+			return
+		}
+		Info("Поиск %s удалён", args[0])
+	},
+}
+
+func init() {
+	default_path := filepath.Join(dataDir(), "schedules.json")
+	DaemonCmd.Flags().StringVar(&schedules_path, "schedules", default_path, "Файл со списком сохранённых поисков")
+	ScheduleCmd.PersistentFlags().StringVar(&schedules_path, "schedules", default_path, "Файл со списком сохранённых поисков")
+
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Query, "query", "q", "", "Строка для поиска")
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Location, "location", "l", "rossiya", "Фильтр по региону")
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Category, "category", "c", "", "Фильтр по категории")
+	ScheduleAddCmd.Flags().Int64VarP(&saved_search.MaxItems, "max", "m", 1,
+		"Максимальное количество элементов для поиска (0 - без ограничения)")
+	ScheduleAddCmd.Flags().StringVar(&saved_search.Since, "since", "",
+		"Не обходить объявления старше периода, отсчитываемого от каждого запуска (примеры: 24h, 7d)")
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Schedule, "schedule", "s", "", "Расписание (every 15m, cron)")
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Output, "output", "o", "",
+		"Файл для результатов последнего запуска")
+	ScheduleAddCmd.Flags().StringVarP(&saved_search.Format, "format", "f", "", "Формат вывода: csv, jsonl, xlsx")
+
+	ScheduleCmd.AddCommand(ScheduleAddCmd)
+	ScheduleCmd.AddCommand(ScheduleListCmd)
+	ScheduleCmd.AddCommand(ScheduleRemoveCmd)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var months = map[string]time.Month{
+	"января":   time.January,
+	"февраля":  time.February,
+	"марта":    time.March,
+	"апреля":   time.April,
+	"мая":      time.May,
+	"июня":     time.June,
+	"июля":     time.July,
+	"августа":  time.August,
+	"сентября": time.September,
+	"октября":  time.October,
+	"ноября":   time.November,
+	"декабря":  time.December,
+	"май":      time.May,
+}
+
+var (
+	clock_re     = regexp.MustCompile(`(\d{1,2}):(\d{2})`)
+	day_month_re = regexp.MustCompile(`(\d{1,2})\s+([а-я]+)(?:\s+(\d{4}))?`)
+	numeric_re   = regexp.MustCompile(`(\d{1,2})\.(\d{1,2})\.(\d{4})`)
+	ago_re       = regexp.MustCompile(`(?:(\d+)\s+)?(секунд|минут|час|день|дн|недел|месяц)[а-я]*\s+назад`)
+)
+
+// Месяц по названию в родительном падеже или сокращению ("мар", "сент")
+func parseMonth(name string) (time.Month, bool) {
+	if month, ok := months[name]; ok {
+		return month, true
+	}
+	if len([]rune(name)) < 3 {
+		return 0, false
+	}
+	for full, month := range months {
+		if strings.HasPrefix(full, name) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+// Относительная дата: "5 минут назад", "час назад", "2 недели назад"
+func parseAgo(s string, now time.Time) (time.Time, bool) {
+	m := ago_re.FindStringSubmatch(s)
+	if m == nil {
+		return time.Time{}, false
+	}
+	count := 1
+	if m[1] != "" {
+		count, _ = strconv.Atoi(m[1])
+	}
+	switch m[2] {
+	case "секунд":
+		return now.Add(-time.Duration(count) * time.Second), true
+	case "минут":
+		return now.Add(-time.Duration(count) * time.Minute), true
+	case "час":
+		return now.Add(-time.Duration(count) * time.Hour), true
+	case "день", "дн":
+		return now.AddDate(0, 0, -count), true
+	case "недел":
+		return now.AddDate(0, 0, -7*count), true
+	}
+	return now.AddDate(0, -count, 0), true
+}
+
+// Разбирает дату публикации объявления в том виде, в котором её показывает avito:
+// "сегодня 14:05", "вчера 10:30", "позавчера", "3 марта 14:05", "3 мар 2015",
+// "12.10.2015", "только что", "5 минут назад".
+// Даты без года считаются прошедшими относительно now.
+func parseDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.ToLower(collapseSpaces(s))
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	if strings.Contains(s, "только что") {
+		return now, true
+	}
+	if t, ok := parseAgo(s, now); ok {
+		return t, true
+	}
+
+	hour, minute := 0, 0
+	if m := clock_re.FindStringSubmatch(s); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+	}
+	loc := now.Location()
+	year, month, day := now.Date()
+
+	switch {
+	case strings.Contains(s, "сегодня"):
+	case strings.Contains(s, "позавчера"):
+		year, month, day = now.AddDate(0, 0, -2).Date()
+	case strings.Contains(s, "вчера"):
+		year, month, day = now.AddDate(0, 0, -1).Date()
+	default:
+		if m := numeric_re.FindStringSubmatch(s); m != nil {
+			day, _ = strconv.Atoi(m[1])
+			mon, _ := strconv.Atoi(m[2])
+			year, _ = strconv.Atoi(m[3])
+			month = time.Month(mon)
+			break
+		}
+		m := day_month_re.FindStringSubmatch(s)
+		if m == nil {
+			return time.Time{}, false
+		}
+		var ok bool
+		if month, ok = parseMonth(m[2]); !ok {
+			return time.Time{}, false
+		}
+		day, _ = strconv.Atoi(m[1])
+		if m[3] != "" {
+			year, _ = strconv.Atoi(m[3])
+		} else if time.Date(year, month, day, hour, minute, 0, 0, loc).After(now) {
+			year--
+		}
+	}
+	return time.Date(year, month, day, hour, minute, 0, 0, loc), true
+}
+
+// Разбирает длительность периода: "24h", "90m", "7d", "2w"
+func parsePeriod(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 1 && (s[n-1] == 'd' || s[n-1] == 'w') {
+		count, err := strconv.Atoi(s[:n-1])
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("Неверный период: %s", s)
+		}
+		days := count
+		if s[n-1] == 'w' {
+			days *= 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Неверный период: %s (примеры: 24h, 7d, 2w)", s)
+	}
+	return d, nil
+}
+
+// Разбирает границу для --since: период назад от now ("24h", "7d")
+// или дату ("2026-10-01", "2026-10-01 14:00", "01.10.2026", "вчера", "3 марта")
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if period, err := parsePeriod(s); err == nil {
+		return now.Add(-period), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := parseDate(s, now); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("Неверная граница --since: %s (примеры: 24h, 7d, 2026-10-01)", s)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var msk = time.FixedZone("MSK", 3*60*60)
+
+// 19 октября 2026, понедельник
+var test_now = time.Date(2026, time.October, 19, 15, 30, 0, 0, msk)
+
+func TestParseDate(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"Сегодня, 12:30", time.Date(2026, 10, 19, 12, 30, 0, 0, msk)},
+		{"сегодня 09:05", time.Date(2026, 10, 19, 9, 5, 0, 0, msk)},
+		{"Вчера 18:05", time.Date(2026, 10, 18, 18, 5, 0, 0, msk)},
+		{"позавчера", time.Date(2026, 10, 17, 0, 0, 0, 0, msk)},
+		{"только что", test_now},
+		{"5 минут назад", test_now.Add(-5 * time.Minute)},
+		{"час назад", test_now.Add(-time.Hour)},
+		{"3 часа назад", test_now.Add(-3 * time.Hour)},
+		{"2 дня назад", time.Date(2026, 10, 17, 15, 30, 0, 0, msk)},
+		{"1 день назад", time.Date(2026, 10, 18, 15, 30, 0, 0, msk)},
+		{"2 недели назад", time.Date(2026, 10, 5, 15, 30, 0, 0, msk)},
+		{"месяц назад", time.Date(2026, 9, 19, 15, 30, 0, 0, msk)},
+		{"3 октября 14:05", time.Date(2026, 10, 3, 14, 5, 0, 0, msk)},
+		{"2 октября", time.Date(2026, 10, 2, 0, 0, 0, 0, msk)},
+		{"1 мая", time.Date(2026, 5, 1, 0, 0, 0, 0, msk)},
+		{"3 мар 2015", time.Date(2015, 3, 3, 0, 0, 0, 0, msk)},
+		{"12 сент", time.Date(2026, 9, 12, 0, 0, 0, 0, msk)},
+		// Дата без года в будущем относится к прошлому году
+		{"25 декабря 10:00", time.Date(2025, 12, 25, 10, 0, 0, 0, msk)},
+		{"12.10.2015", time.Date(2015, 10, 12, 0, 0, 0, 0, msk)},
+	} {
+		got, ok := parseDate(c.s, test_now)
+		if !ok {
+			t.Errorf("parseDate(%q): не удалось разобрать", c.s)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("parseDate(%q) = %s, ожидалось %s", c.s, got, c.want)
+		}
+	}
+
+	for _, s := range []string{"", "недавно", "3 смарта", "12 ма"} {
+		if got, ok := parseDate(s, test_now); ok {
+			t.Errorf("parseDate(%q) = %s, ожидалась ошибка", s, got)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"24h", 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{" 1d ", 24 * time.Hour},
+	} {
+		got, err := parsePeriod(c.s)
+		if err != nil || got != c.want {
+			t.Errorf("parsePeriod(%q) = %s, %v, ожидалось %s", c.s, got, err, c.want)
+		}
+	}
+	for _, s := range []string{"", "-5h", "-1d", "-2w", "d", "5x", "неделя"} {
+		if got, err := parsePeriod(s); err == nil {
+			t.Errorf("parsePeriod(%q) = %s, ожидалась ошибка", s, got)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want time.Time
+	}{
+		{"24h", test_now.Add(-24 * time.Hour)},
+		{"7d", test_now.AddDate(0, 0, -7)},
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, msk)},
+		{"2026-10-01 14:00", time.Date(2026, 10, 1, 14, 0, 0, 0, msk)},
+		{"2026-10-01T14:00:00Z", time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC)},
+		{"01.10.2026", time.Date(2026, 10, 1, 0, 0, 0, 0, msk)},
+		{"вчера", time.Date(2026, 10, 18, 0, 0, 0, 0, msk)},
+		{"3 марта", time.Date(2026, 3, 3, 0, 0, 0, 0, msk)},
+	} {
+		got, err := parseSince(c.s, test_now)
+		if err != nil {
+			t.Errorf("parseSince(%q): %s", c.s, err)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("parseSince(%q) = %s, ожидалось %s", c.s, got, c.want)
+		}
+	}
+
+	// Отрицательный период дал бы границу в будущем
+	for _, s := range []string{"-5h", "-1d", "завтра", "2026-13-01"} {
+		if got, err := parseSince(s, test_now); err == nil {
+			t.Errorf("parseSince(%q) = %s, ожидалась ошибка", s, got)
+		}
+	}
+}
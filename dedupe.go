@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Включено ли объединение дубликатов (см. --dedupe)
+var dedupe bool
+
+// Максимальное расстояние Хэмминга между хэшами похожих фотографий
+const PHOTO_HASH_DISTANCE = 6
+
+// Объявление, уже попавшее в один из кластеров
+type clusterEntry struct {
+	cluster  string
+	phone    string
+	grams    map[string]bool
+	hash     uint64
+	has_hash bool
+}
+
+// Deduplicator находит повторно выложенные объявления (в том числе
+// под другими ссылками и в других регионах) и объединяет их в кластеры.
+// Дубликатами считаются объявления с одинаковым телефоном и похожими
+// заголовком и описанием, а также (с photos) с похожей первой фотографией.
+type Deduplicator struct {
+	w         ItemWriter
+	threshold float64
+	photos    bool
+	unique    bool
+
+	mu      sync.Mutex
+	entries []*clusterEntry
+}
+
+func NewDeduplicator(w ItemWriter, threshold float64, photos, unique bool) *Deduplicator {
+	return &Deduplicator{w: w, threshold: threshold, photos: photos, unique: unique}
+}
+
+func (d *Deduplicator) Write(item *Item) error {
+	entry := &clusterEntry{
+		phone: normalizePhone(item.phone),
+		grams: trigrams(item.header + " " + item.description),
+	}
+	if d.photos && len(item.photos) > 0 {
+		entry.hash, entry.has_hash = photoHash(item)
+	}
+
+	d.mu.Lock()
+	cluster := ""
+	for _, e := range d.entries {
+		if d.duplicate(e, entry) {
+			cluster = e.cluster
+			break
+		}
+	}
+	found := cluster != ""
+	if !found {
+		cluster = item.key()
+	}
+	entry.cluster = cluster
+	d.entries = append(d.entries, entry)
+	d.mu.Unlock()
+
+	if found && d.unique {
+		Debug("Skipping duplicate %s (cluster %s)", item.url, cluster)
+		return nil
+	}
+	item.cluster = cluster
+	return d.w.Write(item)
+}
+
+func (d *Deduplicator) Close() error {
+	return d.w.Close()
+}
+
+func (d *Deduplicator) duplicate(a, b *clusterEntry) bool {
+	if a.has_hash && b.has_hash && hammingDistance(a.hash, b.hash) <= PHOTO_HASH_DISTANCE {
+		return true
+	}
+	// Разные телефоны - разные продавцы
+	if a.phone != "" && b.phone != "" && a.phone != b.phone {
+		return false
+	}
+	return similarity(a.grams, b.grams) >= d.threshold
+}
+
+// Последние 10 цифр номера, чтобы +7 и 8 в начале не мешали сравнению
+func normalizePhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return string(digits)
+}
+
+// Множество триграмм текста (без учёта регистра и знаков препинания)
+func trigrams(text string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	runes := []rune(" " + strings.Join(words, " ") + " ")
+	grams := make(map[string]bool)
+	for i := 0; i+3 <= len(runes); i++ {
+		grams[string(runes[i:i+3])] = true
+	}
+	return grams
+}
+
+// Коэффициент Жаккара для двух множеств триграмм
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for g := range a {
+		if b[g] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// Разностный хэш (dHash) первой фотографии объявления.
+// Берётся скачанный файл (--photos-dir), иначе фотография загружается.
+func photoHash(item *Item) (uint64, bool) {
+	var r io.Reader
+	if len(item.photo_paths) > 0 {
+		f, err := os.Open(item.photo_paths[0])
+		if err != nil {
+			return 0, false
+		}
+		defer f.Close()
+		r = f
+	} else {
+		req, err := newRequest(item.photos[0], item.url)
+		if err != nil {
+			return 0, false
+		}
+		throttleWait()
+		res, err := doRequest("photo", req)
+		if err != nil {
+			return 0, false
+		}
+		defer res.Body.Close()
+		data, err := ioutil.ReadAll(res.Body)
+		if err != nil || res.StatusCode != 200 {
+			return 0, false
+		}
+		r = bytes.NewReader(data)
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		Debug("Can't decode photo of %s: %s", item.url, err)
+		return 0, false
+	}
+	return dHash(img), true
+}
+
+// Уменьшает картинку до 9x8 в оттенках серого и сравнивает соседние пиксели
+func dHash(img image.Image) uint64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+
+	var gray [8][9]uint32
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			px := bounds.Min.X + x*w/9 + w/18
+			py := bounds.Min.Y + y*h/8 + h/16
+			r, g, b, _ := img.At(px, py).RGBA()
+			gray[y][x] = (299*r + 587*g + 114*b) / 1000
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+func hammingDistance(a, b uint64) int {
+	distance := 0
+	for x := a ^ b; x != 0; x &= x - 1 {
+		distance++
+	}
+	return distance
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strings"
+)
+
+const DESKTOP_BASE_URL string = "https://www.avito.ru"
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Парсер полной версии сайта (www.avito.ru).
+// Кроме основных полей достаёт параметры объявления,
+// рейтинг продавца и количество просмотров.
+type DesktopParser struct{}
+
+func (p *DesktopParser) SearchURL(location, category, query string) string {
+	return searchURL(DESKTOP_BASE_URL, location, category, query)
+}
+
+func (p *DesktopParser) ParsePage(doc *goquery.Document) (*Page, error) {
+	page := &Page{}
+
+	next_page_url, exists := doc.Find(".js-pagination-next").First().Attr("href")
+	if exists {
+		page.next_url = fmt.Sprintf("%s%s", DESKTOP_BASE_URL, next_page_url)
+	}
+
+	page.category = collapseSpaces(doc.Find(".page-title-text").First().Text())
+	if page.category == "" {
+		return nil, LayoutChanged
+	}
+	page.count = collapseSpaces(doc.Find(".page-title-count").First().Text())
+
+	doc.Find(".js-catalog-item-enum").Each(func(i int, s *goquery.Selection) {
+		link := s.Find(".item-description-title-link").First()
+		item_url, exists := link.Attr("href")
+		if !exists {
+			Error(".item-description-title-link not found")
+			return
+		}
+		item := &Item{}
+		item.header = collapseSpaces(link.Text())
+		item.price = collapseSpaces(s.Find(".about").First().Text())
+		item.location = collapseSpaces(s.Find(".data p").Last().Text())
+		item.date = collapseSpaces(s.Find(".date").First().Text())
+		item.url = fmt.Sprintf("%s%s", DESKTOP_BASE_URL, item_url)
+		item.id = itemID(item.url)
+		page.items = append(page.items, item)
+	})
+	return page, nil
+}
+
+func (p *DesktopParser) ParseItem(doc *goquery.Document, item *Item) []string {
+	var phone_urls []string
+
+	if location := collapseSpaces(doc.Find(".seller-info-value [itemprop=address]").First().Text()); location != "" {
+		item.location = location
+	}
+	if price := collapseSpaces(doc.Find(".price-value-string").First().Text()); price != "" {
+		item.price = price
+	}
+
+	item.description = strings.TrimSpace(doc.Find(".item-description-text").First().Text())
+
+	item.params = nil
+	doc.Find(".item-params-list-item").Each(func(i int, s *goquery.Selection) {
+		item.params = append(item.params, collapseSpaces(s.Text()))
+	})
+
+	item.photos = nil
+	doc.Find(".gallery-img-frame").Each(func(i int, s *goquery.Selection) {
+		if src, exists := s.Attr("data-url"); exists {
+			item.photos = append(item.photos, absoluteURL(DESKTOP_BASE_URL, src))
+		}
+	})
+
+	item.seller = collapseSpaces(doc.Find(".seller-info-name").First().Text())
+	item.seller_type = sellerType(doc.Find(".seller-info-col").First().Text())
+	item.rating = collapseSpaces(doc.Find(".seller-info-rating-score").First().Text())
+	item.views = collapseSpaces(doc.Find(".title-info-views").First().Text())
+
+	doc.Find(".js-item-phone-button").Each(func(i int, s *goquery.Selection) {
+		phone_url, exists := s.Attr("data-phone-url")
+		if exists {
+			Debug("Found phone url: %s", phone_url)
+			phone_urls = append(phone_urls, strings.Join([]string{DESKTOP_BASE_URL, phone_url}, ""))
+		}
+	})
+	return phone_urls
+}
+
+func (p *DesktopParser) SellerURL(doc *goquery.Document) string {
+	return sellerURL(DESKTOP_BASE_URL, doc)
+}
+
+func (p *DesktopParser) ParseSellerPage(doc *goquery.Document) (*Page, error) {
+	page := &Page{}
+
+	next_page_url, exists := doc.Find(".js-pagination-next").First().Attr("href")
+	if exists {
+		page.next_url = fmt.Sprintf("%s%s", DESKTOP_BASE_URL, next_page_url)
+	}
+
+	seller := collapseSpaces(doc.Find(".profile-header-name").First().Text())
+	if seller == "" {
+		return nil, LayoutChanged
+	}
+	page.count = collapseSpaces(doc.Find(".profile-tabs-item-active .profile-tabs-counter").First().Text())
+
+	doc.Find(".profile-item").Each(func(i int, s *goquery.Selection) {
+		link := s.Find(".profile-item-title a").First()
+		item_url, exists := link.Attr("href")
+		if !exists {
+			Error(".profile-item-title not found")
+			return
+		}
+		item := &Item{}
+		item.header = collapseSpaces(link.Text())
+		item.price = collapseSpaces(s.Find(".profile-item-price").First().Text())
+		item.location = collapseSpaces(s.Find(".profile-item-address").First().Text())
+		item.date = collapseSpaces(s.Find(".profile-item-date").First().Text())
+		item.url = absoluteURL(DESKTOP_BASE_URL, item_url)
+		item.id = itemID(item.url)
+		item.seller = seller
+		page.items = append(page.items, item)
+	})
+	return page, nil
+}
+
+func collapseSpaces(s string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+// Изменение одного поля объявления
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Объявление, которое есть в обоих файлах, но изменилось
+type ItemChange struct {
+	ID      string        `json:"id"`
+	URL     string        `json:"url"`
+	Header  string        `json:"header"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// Результат сравнения двух выгрузок
+type Diff struct {
+	Added   []*Item      `json:"added"`
+	Removed []*Item      `json:"removed"`
+	Changed []ItemChange `json:"changed"`
+}
+
+// Сравнивает две выгрузки по ID объявления
+func diffItems(old_items, new_items []*Item) *Diff {
+	diff := &Diff{Added: []*Item{}, Removed: []*Item{}, Changed: []ItemChange{}}
+
+	old_index := make(map[string]*Item)
+	for _, item := range latestItemsInOrder(old_items) {
+		old_index[item.key()] = item
+	}
+	new_index := make(map[string]bool)
+
+	for _, item := range latestItemsInOrder(new_items) {
+		new_index[item.key()] = true
+		old, ok := old_index[item.key()]
+		if !ok {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+
+		var changes []FieldChange
+		if old.price != item.price {
+			changes = append(changes, FieldChange{"price", old.price, item.price})
+		}
+		if old.header != item.header {
+			changes = append(changes, FieldChange{"header", old.header, item.header})
+		}
+		if old.description != item.description {
+			changes = append(changes, FieldChange{"description", old.description, item.description})
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ItemChange{item.id, item.url, item.header, changes})
+		}
+	}
+
+	for _, item := range latestItemsInOrder(old_items) {
+		if !new_index[item.key()] {
+			diff.Removed = append(diff.Removed, item)
+		}
+	}
+	return diff
+}
+
+// Последняя запись для каждого объявления в порядке первого появления
+// (одно объявление может встречаться несколько раз, например с разными телефонами)
+func latestItemsInOrder(all []*Item) []*Item {
+	index := make(map[string]int)
+	var items []*Item
+	for _, item := range all {
+		if i, ok := index[item.key()]; ok {
+			items[i] = item
+			continue
+		}
+		index[item.key()] = len(items)
+		items = append(items, item)
+	}
+	return items
+}
+
+func (diff *Diff) Print(w io.Writer) {
+	fmt.Fprintf(w, "Новые объявления: %d\n", len(diff.Added))
+	for _, item := range diff.Added {
+		fmt.Fprintf(w, "  + %s | %s | %s\n", item.header, item.price, item.url)
+	}
+
+	fmt.Fprintf(w, "\nСнятые объявления: %d\n", len(diff.Removed))
+	for _, item := range diff.Removed {
+		fmt.Fprintf(w, "  - %s | %s | %s\n", item.header, item.price, item.url)
+	}
+
+	fmt.Fprintf(w, "\nИзменённые объявления: %d\n", len(diff.Changed))
+	names := map[string]string{"price": "цена", "header": "заголовок", "description": "описание"}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(w, "  * %s | %s\n", change.Header, change.URL)
+		for _, c := range change.Changes {
+			if c.Field == "description" {
+				fmt.Fprintf(w, "      %s изменено\n", names[c.Field])
+				continue
+			}
+			fmt.Fprintf(w, "      %s: %s -> %s\n", names[c.Field], c.Old, c.New)
+		}
+	}
+}
+
+var diff_json bool
+
+var DiffCmd = &cobra.Command{
+	Use:     "diff <старый файл> <новый файл>",
+	Short:   "Сравнить результаты двух запусков",
+	Long:    "Сравнивает две выгрузки (jsonl или csv) по ID объявления: новые, снятые объявления, изменения цены, заголовка и описания.",
+	Example: "selavito diff old.jsonl new.jsonl\nselavito diff old.jsonl new.jsonl --json | jq '.changed[]'",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 2 {
+			cmd.Help()
+			return
+		}
+		old_items, err := readItemsFile(args[0])
+		if err != nil {
+			fail(err)
+			return
+		}
+		new_items, err := readItemsFile(args[1])
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		diff := diffItems(old_items, new_items)
+		if diff_json {
+			enc := json.NewEncoder(os.Stdout)
+			if err := enc.Encode(diff); err != nil {
+				fail(err)
+			}
+			return
+		}
+		diff.Print(os.Stdout)
+	},
+}
+
+func init() {
+	DiffCmd.Flags().BoolVar(&diff_json, "json", false, "Вывести результат в формате JSON")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Коды завершения процесса
+const (
+	EXIT_OK      = 0
+	EXIT_ERROR   = 1 // прочие ошибки (неверные параметры и т.п.)
+	EXIT_BANNED  = 2 // IP забанили
+	EXIT_LAYOUT  = 3 // изменилась разметка сайта
+	EXIT_NETWORK = 4 // сетевая ошибка
+	EXIT_OUTPUT  = 5 // не удалось сохранить результаты
+	EXIT_PARTIAL = 6 // часть объявлений не обработана (см. --fail-on-partial)
+	EXIT_BLOCKED = 7 // сайт показал капчу или ограничил доступ
+)
+
+// Сетевая ошибка при запросе к сайту
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("Сетевая ошибка: %s", e.Err)
+}
+
+// Ошибка при сохранении результатов
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("Ошибка сохранения результатов: %s", e.Err)
+}
+
+// Часть объявлений не удалось обработать
+type PartialError struct {
+	Failed int
+	First  error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("Не удалось обработать объявлений: %d (первая ошибка: %s)", e.Failed, e.First)
+}
+
+func exitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return EXIT_OK
+	case *NetworkError:
+		return EXIT_NETWORK
+	case *OutputError:
+		return EXIT_OUTPUT
+	case *PartialError:
+		return EXIT_PARTIAL
+	}
+	switch err {
+	case IPBanned:
+		return EXIT_BANNED
+	case LayoutChanged:
+		return EXIT_LAYOUT
+	case Blocked:
+		return EXIT_BLOCKED
+	}
+	return EXIT_ERROR
+}
+
+// Код завершения процесса - по первой ошибке
+var exit_code = EXIT_OK
+
+// Выводит ошибку и запоминает код завершения процесса
+func fail(err error) {
+	Error(err.Error())
+	if exit_code == EXIT_OK {
+		exit_code = exitCode(err)
+	}
+}
+
+func exit() {
+	os.Exit(exit_code)
+}
+
+// Объявления, которые не удалось обработать (загрузить, получить телефон, сохранить)
+var item_failures struct {
+	sync.Mutex
+	count int
+	first error
+}
+
+func itemFailed(err error) {
+	item_failures.Lock()
+	defer item_failures.Unlock()
+	if item_failures.count == 0 {
+		item_failures.first = err
+	}
+	item_failures.count++
+}
+
+// Завершает запуск: выводит ошибку обхода и количество необработанных
+// объявлений. С --fail-on-partial необработанные объявления считаются ошибкой.
+func finishRun(err error) {
+	if err != nil {
+		fail(err)
+	}
+
+	item_failures.Lock()
+	partial := &PartialError{item_failures.count, item_failures.first}
+	item_failures.Unlock()
+	if partial.Failed == 0 {
+		return
+	}
+	if options.fail_on_partial {
+		fail(partial)
+		return
+	}
+	Error(partial.Error())
+}
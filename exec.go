@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Что делать, если команда завершилась с ошибкой
+const (
+	EXEC_SKIP  = "skip"  // пропустить объявление
+	EXEC_RETRY = "retry" // повторить (не больше retries раз), затем пропустить
+	EXEC_ABORT = "abort" // остановить обход
+)
+
+// ExecWriter запускает команду для каждого объявления и передаёт ей
+// объявление в формате JSON на stdin. Вывод команды передаётся в stdout
+// целиком после её завершения, чтобы выводы разных запусков не смешивались.
+type ExecWriter struct {
+	command string
+	policy  string
+	retries int
+	abort   func()
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// concurrency - сколько команд может выполняться одновременно,
+// abort вызывается (один раз) при ошибке с политикой abort.
+func NewExecWriter(command, policy string, retries, concurrency int, abort func()) (*ExecWriter, error) {
+	switch policy {
+	case EXEC_SKIP, EXEC_RETRY, EXEC_ABORT:
+	default:
+		return nil, fmt.Errorf("Неизвестная политика ошибок --exec: %s (допустимо: skip, retry, abort)", policy)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ExecWriter{
+		command: command,
+		policy:  policy,
+		retries: retries,
+		abort:   abort,
+		slots:   make(chan struct{}, concurrency),
+	}, nil
+}
+
+func (e *ExecWriter) Write(item *Item) error {
+	if err := e.failed(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	e.slots <- struct{}{}
+	e.wg.Add(1)
+	go func() {
+		defer func() {
+			<-e.slots
+			e.wg.Done()
+		}()
+		e.process(item, data)
+	}()
+	return nil
+}
+
+func (e *ExecWriter) process(item *Item, data []byte) {
+	attempts := 1
+	if e.policy == EXEC_RETRY {
+		attempts += e.retries
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * time.Second)
+			Debug("Retrying command for %s (%d/%d)", item.url, i, e.retries)
+		}
+		if err = e.run(data); err == nil {
+			return
+		}
+	}
+
+	Error("Команда завершилась с ошибкой для %s: %s", item.url, err)
+	itemFailed(&OutputError{err})
+	if e.policy != EXEC_ABORT {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err == nil {
+		e.err = fmt.Errorf("Команда %q завершилась с ошибкой: %s", e.command, err)
+		if e.abort != nil {
+			e.abort()
+		}
+	}
+}
+
+// Вывод команд в stdout по одному запуску за раз
+var exec_output sync.Mutex
+
+func (e *ExecWriter) run(data []byte) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", e.command)
+	} else {
+		cmd = exec.Command("sh", "-c", e.command)
+	}
+	var output bytes.Buffer
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+
+	exec_output.Lock()
+	os.Stdout.Write(output.Bytes())
+	exec_output.Unlock()
+	return err
+}
+
+func (e *ExecWriter) failed() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// Дожидается завершения всех запущенных команд
+func (e *ExecWriter) Close() error {
+	e.wg.Wait()
+	return e.failed()
+}
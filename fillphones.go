@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+// Дополняет телефонами объявления, сохранённые без них (см. --no-phones).
+// Объявления с телефоном и те, для которых телефон получить не удалось,
+// сохраняются без изменений. После бана или капчи (см. fatalError)
+// оставшиеся объявления сохраняются как есть, чтобы не потерять их.
+func fillPhones(all []*Item, items chan<- *Item, stop <-chan struct{}) error {
+	var banned error
+	for _, item := range all {
+		if stopped(stop) {
+			break
+		}
+		if item.phone != "" || banned != nil {
+			items <- item
+			continue
+		}
+
+		phone_urls, err := fetchItem(parserForURL(item.url), item)
+		if err != nil {
+			itemFailed(err)
+			if fatalError(err) {
+				banned = err
+			} else {
+				Error("%s", err.Error())
+			}
+			items <- item
+			continue
+		}
+		downloadPhotos(item)
+
+		var phones []string
+		for _, phone_url := range phone_urls {
+			phone, err := getPhone(phone_url, item.url)
+			if err != nil {
+				itemFailed(err)
+				if fatalError(err) {
+					banned = err
+					break
+				}
+				Error("%s", err.Error())
+				continue
+			}
+			phones = append(phones, phone)
+		}
+		if len(phones) == 0 {
+			items <- item
+			continue
+		}
+		emitPhones(item, phones, items)
+	}
+	return banned
+}
+
+var fill_out OutputOptions
+
+var FillPhonesCmd = &cobra.Command{
+	Use:   "fill-phones <файл с результатами>",
+	Short: "Получить телефоны для объявлений, сохранённых без них",
+	Long: `Читает результаты (jsonl или csv), сохранённые с --no-phones, и запрашивает телефоны
+только для объявлений без телефона. Результат сохраняется так же, как при обычном поиске.`,
+	Example: "selavito -l moskva -q кресло -m 100 --no-phones --price-max 5000 -o kreslo.jsonl\nselavito fill-phones kreslo.jsonl -o kreslo_phones.csv",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 1 || fill_out.Empty() {
+			cmd.Help()
+			return
+		}
+
+		// Файл читается целиком до открытия результатов,
+		// поэтому можно перезаписать тот же файл
+		all, err := readItemsFile(args[0])
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		w, err := fill_out.Open()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		finish, err := startSession()
+		if err != nil {
+			fail(err)
+			w.Close()
+			return
+		}
+		defer finish()
+
+		count, err := runCrawl(w, func(items chan<- *Item) error {
+			return fillPhones(all, items, fill_out.Stop())
+		})
+		Info("Сохранено объявлений: %d", count)
+		finishRun(err)
+	},
+}
+
+func init() {
+	fill_out.AddFlags(FillPhonesCmd)
+}
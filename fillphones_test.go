@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// После капчи fill-phones больше не запрашивает объявления,
+// но сохраняет все оставшиеся как есть
+func TestFillPhonesStopsOnBlocked(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><form action="/captcha"><input name="captcha"></form></body></html>`)
+	}))
+	defer server.Close()
+	initClient(nil)
+	options.interactive = false
+
+	all := []*Item{
+		{id: "1", url: server.URL + "/moskva/mebel/kreslo_1"},
+		{id: "2", url: server.URL + "/moskva/mebel/stol_2", phone: "89001112233"},
+		{id: "3", url: server.URL + "/moskva/mebel/divan_3"},
+		{id: "4", url: server.URL + "/moskva/mebel/shkaf_4"},
+	}
+	items := make(chan *Item, len(all))
+	err := fillPhones(all, items, nil)
+	close(items)
+
+	if err != Blocked {
+		t.Errorf("fillPhones: %v, ожидалось Blocked", err)
+	}
+	if requests != 1 {
+		t.Errorf("запросов после капчи: %d, ожидался 1", requests)
+	}
+	saved := 0
+	for range items {
+		saved++
+	}
+	if saved != len(all) {
+		t.Errorf("сохранено объявлений: %d, ожидалось %d", saved, len(all))
+	}
+}
+
+// Повторная загрузка объявления из файла не меняет время обнаружения и дату публикации
+func TestFetchItemKeepsDates(t *testing.T) {
+	html, err := ioutil.ReadFile(filepath.Join("testdata", "mobile_item.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+	}))
+	defer server.Close()
+	initClient(nil)
+
+	seen := time.Date(2026, 10, 1, 9, 0, 0, 0, msk)
+	published := time.Date(2026, 9, 30, 12, 30, 0, 0, msk)
+	item := &Item{url: server.URL + "/item", date: "Сегодня, 12:30", seen: seen, published: published}
+	if _, err := fetchItem(&MobileParser{}, item); err != nil {
+		t.Fatal(err)
+	}
+	if !item.seen.Equal(seen) || !item.published.Equal(published) {
+		t.Errorf("seen: %v, published: %v, ожидалось %v и %v", item.seen, item.published, seen, published)
+	}
+
+	// Без даты публикации относительная дата считается от времени обнаружения
+	item = &Item{url: server.URL + "/item", date: "Сегодня, 12:30", seen: seen}
+	if _, err := fetchItem(&MobileParser{}, item); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 1, 12, 30, 0, 0, msk); !item.published.Equal(want) {
+		t.Errorf("published: %v, ожидалось %v", item.published, want)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule - одно условие отбора объявлений
+type Rule interface {
+	Name() string
+
+	// Можно ли проверить условие по данным со страницы поиска,
+	// не загружая страницу объявления
+	Listing() bool
+
+	Match(item *Item) bool
+}
+
+// Filter - цепочка правил между разбором объявлений и сохранением.
+// Объявления отбираются до запроса телефонов, чтобы телефоны
+// (самые частые причины бана) запрашивались только для подходящих.
+// Нулевой *Filter пропускает все объявления.
+type Filter struct {
+	rules []Rule
+
+	mu       sync.Mutex
+	rejected map[string]int
+}
+
+func NewFilter(rules ...Rule) *Filter {
+	return &Filter{rules: rules, rejected: make(map[string]int)}
+}
+
+// Фильтр с дополнительными правилами (f может быть nil)
+func (f *Filter) With(rules ...Rule) *Filter {
+	if f == nil {
+		return NewFilter(rules...)
+	}
+	return NewFilter(append(f.rules[:len(f.rules):len(f.rules)], rules...)...)
+}
+
+// Проверка по данным со страницы поиска
+func (f *Filter) MatchListing(item *Item) bool {
+	return f.match(item, true)
+}
+
+// Проверка после загрузки страницы объявления
+func (f *Filter) Match(item *Item) bool {
+	return f.match(item, false)
+}
+
+func (f *Filter) match(item *Item, listing bool) bool {
+	if f == nil {
+		return true
+	}
+	for _, rule := range f.rules {
+		if rule.Listing() != listing {
+			continue
+		}
+		if !rule.Match(item) {
+			Debug("Skipping %s: rule %s", item.url, rule.Name())
+			f.mu.Lock()
+			f.rejected[rule.Name()]++
+			f.mu.Unlock()
+			return false
+		}
+	}
+	return true
+}
+
+// Выводит, сколько объявлений отклонило каждое правило
+func (f *Filter) Report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rule := range f.rules {
+		Info("Отклонено правилом %s: %d", rule.Name(), f.rejected[rule.Name()])
+	}
+}
+
+// Граница по дате публикации из --max-age (нулевое время, если не задана)
+func (f *Filter) Since() time.Time {
+	var since time.Time
+	if f == nil {
+		return since
+	}
+	for _, rule := range f.rules {
+		if r, ok := rule.(*dateRule); ok && r.since.After(since) {
+			since = r.since
+		}
+	}
+	return since
+}
+
+// Цена в заданных пределах (0 - без ограничения)
+type priceRule struct {
+	min, max int64
+}
+
+func (r *priceRule) Name() string  { return "price" }
+func (r *priceRule) Listing() bool { return true }
+
+func (r *priceRule) Match(item *Item) bool {
+	price, ok := parsePrice(item.price)
+	if !ok {
+		return false
+	}
+	return (r.min == 0 || price >= r.min) && (r.max == 0 || price <= r.max)
+}
+
+// Объявление опубликовано не раньше since.
+// Объявления с неизвестной датой публикации пропускаются.
+type dateRule struct {
+	since time.Time
+}
+
+func (r *dateRule) Name() string  { return "date" }
+func (r *dateRule) Listing() bool { return true }
+
+func (r *dateRule) Match(item *Item) bool {
+	published := item.published
+	if published.IsZero() {
+		var ok bool
+		if published, ok = parseDate(item.date, time.Now()); !ok {
+			return true
+		}
+	}
+	return !published.Before(r.since)
+}
+
+// Заголовок и описание в нижнем регистре
+func itemText(item *Item) string {
+	return strings.ToLower(item.header + "\n" + item.description)
+}
+
+// Ключевое слово должно встречаться в заголовке или описании
+type keywordRule struct {
+	word string
+}
+
+func (r *keywordRule) Name() string  { return fmt.Sprintf("keyword %q", r.word) }
+func (r *keywordRule) Listing() bool { return false }
+
+func (r *keywordRule) Match(item *Item) bool {
+	return strings.Contains(itemText(item), strings.ToLower(r.word))
+}
+
+// Стоп-слово не должно встречаться в заголовке или описании
+type stopWordRule struct {
+	word string
+}
+
+func (r *stopWordRule) Name() string  { return fmt.Sprintf("stop-word %q", r.word) }
+func (r *stopWordRule) Listing() bool { return false }
+
+func (r *stopWordRule) Match(item *Item) bool {
+	return !strings.Contains(itemText(item), strings.ToLower(r.word))
+}
+
+// Регулярное выражение (без учёта регистра) должно совпасть с заголовком
+// или описанием, а с exclude - наоборот, не должно
+type regexpRule struct {
+	re      *regexp.Regexp
+	exclude bool
+}
+
+func newRegexpRule(expr string, exclude bool) (*regexpRule, error) {
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("Неверное регулярное выражение %s: %s", expr, err)
+	}
+	return &regexpRule{re, exclude}, nil
+}
+
+func (r *regexpRule) Name() string {
+	if r.exclude {
+		return fmt.Sprintf("exclude %q", r.re.String()[4:])
+	}
+	return fmt.Sprintf("match %q", r.re.String()[4:])
+}
+
+func (r *regexpRule) Listing() bool { return false }
+
+func (r *regexpRule) Match(item *Item) bool {
+	return r.re.MatchString(item.header+"\n"+item.description) != r.exclude
+}
+
+// Тип продавца: private или company
+type sellerTypeRule struct {
+	seller_type string
+}
+
+func (r *sellerTypeRule) Name() string  { return "seller-type" }
+func (r *sellerTypeRule) Listing() bool { return false }
+
+func (r *sellerTypeRule) Match(item *Item) bool {
+	return item.seller_type == r.seller_type
+}
+
+// Фильтр объявлений до запроса телефонов (см. --no-phones)
+var item_filter *Filter
+
+// Не запрашивать телефоны
+var no_phones bool
+
+// Параметры отбора объявлений. Задаются параметрами командной строки
+// или в файле настроек (JSON), параметры дополняют файл.
+type FilterOptions struct {
+	PriceMin   int64    `json:"price_min"`
+	PriceMax   int64    `json:"price_max"`
+	Keywords   []string `json:"keywords"`
+	StopWords  []string `json:"stop_words"`
+	Match      []string `json:"match"`
+	Exclude    []string `json:"exclude"`
+	SellerType string   `json:"seller_type"`
+	MaxAge     string   `json:"max_age"`
+
+	config string
+}
+
+func (o *FilterOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&no_phones, "no-phones", false,
+		"Не запрашивать телефоны (их можно получить позже командой fill-phones)")
+	cmd.Flags().Int64Var(&o.PriceMin, "price-min", 0, "Минимальная цена")
+	cmd.Flags().Int64Var(&o.PriceMax, "price-max", 0, "Максимальная цена")
+	cmd.Flags().StringSliceVar(&o.Keywords, "keyword", nil,
+		"Ключевые слова, которые должны быть в заголовке или описании (через запятую)")
+	cmd.Flags().StringSliceVar(&o.StopWords, "stop-word", nil,
+		"Стоп-слова: объявления с ними в заголовке или описании отбрасываются (через запятую)")
+	cmd.Flags().StringSliceVar(&o.Match, "match", nil,
+		"Регулярное выражение, которому должны соответствовать заголовок или описание")
+	cmd.Flags().StringSliceVar(&o.Exclude, "exclude", nil,
+		"Регулярное выражение: совпавшие объявления отбрасываются")
+	cmd.Flags().StringVar(&o.SellerType, "seller-type", "",
+		"Тип продавца: private (частное лицо) или company (компания)")
+	cmd.Flags().StringVar(&o.MaxAge, "max-age", "",
+		"Только объявления, опубликованные за указанный период (примеры: 24h, 3d, 2w); при поиске, как и --since, останавливает обход")
+	cmd.Flags().StringVar(&o.config, "filter-config", filepath.Join(dataDir(), "filters.json"),
+		"Файл с настройками фильтров (JSON, если файла нет - не используется)")
+}
+
+// Собирает фильтр из файла настроек и параметров (nil, если ничего не задано)
+func (o *FilterOptions) Filter() (*Filter, error) {
+	var config FilterOptions
+	if o.config != "" {
+		if err := readJSONFile(o.config, &config); err != nil {
+			return nil, err
+		}
+	}
+	if o.PriceMin > 0 {
+		config.PriceMin = o.PriceMin
+	}
+	if o.PriceMax > 0 {
+		config.PriceMax = o.PriceMax
+	}
+	if o.SellerType != "" {
+		config.SellerType = o.SellerType
+	}
+	if o.MaxAge != "" {
+		config.MaxAge = o.MaxAge
+	}
+	config.Keywords = append(config.Keywords, o.Keywords...)
+	config.StopWords = append(config.StopWords, o.StopWords...)
+	config.Match = append(config.Match, o.Match...)
+	config.Exclude = append(config.Exclude, o.Exclude...)
+
+	var rules []Rule
+	if config.PriceMin > 0 || config.PriceMax > 0 {
+		rules = append(rules, &priceRule{config.PriceMin, config.PriceMax})
+	}
+	if config.MaxAge != "" {
+		period, err := parsePeriod(config.MaxAge)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &dateRule{time.Now().Add(-period)})
+	}
+	switch config.SellerType {
+	case "":
+	case "private", "company":
+		rules = append(rules, &sellerTypeRule{config.SellerType})
+	default:
+		return nil, fmt.Errorf("Неизвестный тип продавца: %s (допустимо: private, company)", config.SellerType)
+	}
+	for _, word := range config.StopWords {
+		rules = append(rules, &stopWordRule{word})
+	}
+	for _, word := range config.Keywords {
+		rules = append(rules, &keywordRule{word})
+	}
+	for _, expr := range config.Exclude {
+		rule, err := newRegexpRule(expr, true)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	for _, expr := range config.Match {
+		rule, err := newRegexpRule(expr, false)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return NewFilter(rules...), nil
+}
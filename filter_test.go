@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Граница --max-age, которая останавливает обход вместе с --since
+func TestFilterSince(t *testing.T) {
+	var f *Filter
+	if since := f.Since(); !since.IsZero() {
+		t.Errorf("пустой фильтр: %v", since)
+	}
+
+	before := time.Now()
+	f, err := (&FilterOptions{MaxAge: "2d"}).Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = f.With(&priceRule{100, 0})
+	since := f.Since()
+	if since.Before(before.Add(-48*time.Hour)) || since.After(time.Now().Add(-48*time.Hour)) {
+		t.Errorf("since: %v, ожидалось около %v", since, before.Add(-48*time.Hour))
+	}
+
+	f = NewFilter(&priceRule{100, 0})
+	if since := f.Since(); !since.IsZero() {
+		t.Errorf("фильтр без --max-age: %v", since)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"os"
+	"sort"
+	"time"
+)
+
+// Цена объявления на момент, когда его нашли
+type PricePoint struct {
+	Seen  time.Time `json:"seen"`
+	Price string    `json:"price"`
+}
+
+// История цены объявления
+type PriceHistory struct {
+	ID      string       `json:"id"`
+	URL     string       `json:"url"`
+	Header  string       `json:"header"`
+	History []PricePoint `json:"history"`
+
+	// Снижение цены за период (в процентах), см. priceDrop
+	Drop float64 `json:"drop,omitempty"`
+}
+
+// Собирает историю цен из всех записей хранилища.
+// В историю попадают только изменения цены.
+func priceHistories(all []*Item) map[string]*PriceHistory {
+	histories := make(map[string]*PriceHistory)
+	for _, item := range all {
+		if item.price == "" {
+			continue
+		}
+		h, ok := histories[item.key()]
+		if !ok {
+			h = &PriceHistory{ID: item.id}
+			histories[item.key()] = h
+		}
+		h.URL = item.url
+		h.Header = item.header
+		if n := len(h.History); n > 0 && h.History[n-1].Price == item.price {
+			continue
+		}
+		h.History = append(h.History, PricePoint{Seen: item.seen, Price: item.price})
+	}
+	return histories
+}
+
+// Снижение цены (в процентах) с начала периода до текущей цены.
+// Ценой на начало периода считается последняя известная до его начала,
+// а если объявление появилось позже - первая цена в периоде.
+func (h *PriceHistory) priceDrop(since time.Time) (float64, bool) {
+	if len(h.History) < 2 {
+		return 0, false
+	}
+	start := h.History[0]
+	for _, p := range h.History {
+		if p.Seen.After(since) {
+			break
+		}
+		start = p
+	}
+	start_price, ok1 := parsePrice(start.Price)
+	current_price, ok2 := parsePrice(h.History[len(h.History)-1].Price)
+	if !ok1 || !ok2 || start_price <= 0 {
+		return 0, false
+	}
+	return float64(start_price-current_price) * 100 / float64(start_price), true
+}
+
+type byDrop []*PriceHistory
+
+func (s byDrop) Len() int           { return len(s) }
+func (s byDrop) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byDrop) Less(i, j int) bool { return s[i].Drop > s[j].Drop }
+
+var (
+	history_json   bool
+	history_drop   float64
+	history_period string
+)
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history [<id объявления>|<ссылка>]",
+	Short: "История цен объявления по данным локального хранилища",
+	Long: `Показывает историю цены объявления по всем запускам, сохранённым в локальном хранилище (см. --store).
+С параметром --price-drop выводит объявления, цена которых снизилась не меньше чем на заданный процент за период --period.`,
+	Example: "selavito history 123456789\nselavito history https://m.avito.ru/moskva/mebel/kreslo_123456789\nselavito history --price-drop 10 --period 7d",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 1 && history_drop <= 0 {
+			cmd.Help()
+			return
+		}
+		if options.store == "" {
+			fail(errors.New("Не указано локальное хранилище (--store)"))
+			return
+		}
+		all, err := OpenStore(options.store).ReadAll()
+		if err != nil {
+			fail(err)
+			return
+		}
+		histories := priceHistories(all)
+
+		var result []*PriceHistory
+		if len(args) == 1 {
+			key := args[0]
+			if id := itemID(key); id != "" {
+				key = id
+			}
+			h, ok := histories[key]
+			if !ok {
+				fail(fmt.Errorf("Объявление %s не найдено в хранилище", args[0]))
+				return
+			}
+			result = append(result, h)
+		} else {
+			period, err := parsePeriod(history_period)
+			if err != nil {
+				fail(err)
+				return
+			}
+			since := time.Now().Add(-period)
+			for _, h := range histories {
+				if drop, ok := h.priceDrop(since); ok && drop >= history_drop {
+					h.Drop = drop
+					result = append(result, h)
+				}
+			}
+			sort.Sort(byDrop(result))
+		}
+
+		if history_json {
+			enc := json.NewEncoder(os.Stdout)
+			for _, h := range result {
+				if err := enc.Encode(h); err != nil {
+					fail(err)
+					return
+				}
+			}
+			return
+		}
+		for _, h := range result {
+			fmt.Printf("%s | %s\n", h.Header, h.URL)
+			if h.Drop > 0 {
+				fmt.Printf("  снижение цены: %.1f%%\n", h.Drop)
+			}
+			for _, p := range h.History {
+				fmt.Printf("  %s  %s\n", p.Seen.Local().Format("02.01.2006 15:04"), p.Price)
+			}
+		}
+	},
+}
+
+func init() {
+	HistoryCmd.Flags().BoolVar(&history_json, "json", false, "Вывести результат в формате JSON Lines")
+	HistoryCmd.Flags().Float64Var(&history_drop, "price-drop", 0,
+		"Показать объявления, цена которых снизилась не меньше чем на заданный процент")
+	HistoryCmd.Flags().StringVar(&history_period, "period", "7d", "Период для --price-drop (примеры: 24h, 7d, 2w)")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const USER_AGENT string = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/46.0.2490.80 Safari/537.36"
+
+// Общий HTTP клиент для всех запросов (страницы, объявления, телефоны)
+var client *http.Client = &http.Client{}
+
+func initClient(jar http.CookieJar) {
+	client = &http.Client{Jar: jar}
+}
+
+func newRequest(url, referer string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", USER_AGENT)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	return req, nil
+}
+
+// Выполняет запрос и учитывает его в метриках.
+// kind - тип запроса: page, item, phone или photo.
+func doRequest(kind string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		metrics.ObserveRequest(kind, "error", time.Since(start))
+		return nil, &NetworkError{err}
+	}
+	metrics.ObserveRequest(kind, strconv.Itoa(res.StatusCode), time.Since(start))
+	if res.StatusCode == 403 {
+		metrics.Ban()
+	}
+	return res, nil
+}
+
+// Загружает и разбирает HTML страницу. Если сайт показал капчу,
+// запрос повторяется после разблокировки (см. waitUnblock).
+func fetchDocument(kind, url, referer string) (*goquery.Document, error) {
+	for {
+		generation := unblockGeneration()
+		doc, err := fetchPage(kind, url, referer)
+		if err == Blocked && waitUnblock(url, generation) {
+			throttleWait()
+			continue
+		}
+		return doc, err
+	}
+}
+
+func fetchPage(kind, url, referer string) (*goquery.Document, error) {
+	req, err := newRequest(url, referer)
+	if err != nil {
+		return nil, err
+	}
+	res, err := doRequest(kind, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 403 {
+		return nil, IPBanned
+	}
+	if res.StatusCode == 429 {
+		metrics.Ban()
+		return nil, Blocked
+	}
+
+	body, err := decodeBody(res.Body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, err
+	}
+	doc.Url = res.Request.URL
+	if blockedPage(doc) {
+		metrics.Ban()
+		return nil, Blocked
+	}
+	return doc, nil
+}
+
+// Каталог для хранения служебных файлов (~/.selavito)
+func dataDir() string {
+	home := os.Getenv("HOME")
+	if runtime.GOOS == "windows" {
+		home = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(home, ".selavito")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Читает объявления из файла с результатами (jsonl или csv).
+// Формат определяется по расширению файла, "-" - стандартный ввод.
+func readItemsFile(path string) ([]*Item, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	switch format := formatByPath(path); format {
+	case "jsonl":
+		return readJSONLines(r)
+	case "csv":
+		return readCSV(r)
+	default:
+		return nil, fmt.Errorf("Чтение формата %s не поддерживается (допустимо: csv, jsonl)", format)
+	}
+}
+
+// Читает csv файл, записанный CSVWriter. Разделитель определяется по первой строке,
+// BOM пропускается. Если первая строка - названия колонок (ru или en),
+// колонки сопоставляются по ним, иначе ожидаются колонки по умолчанию:
+// заголовок, местоположение, телефон, ссылка.
+func readCSV(r io.Reader) ([]*Item, error) {
+	return readCSVColumns(r, nil)
+}
+
+// Читает csv файл, cols - колонки файла без строки названий (nil - по умолчанию)
+func readCSVColumns(r io.Reader, cols []*column) ([]*Item, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte(UTF8_BOM))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
+	reader.FieldsPerRecord = -1
+
+	if cols == nil {
+		cols, _ = parseColumns([]string{"header", "location", "phone", "url"})
+	}
+	first := true
+	var items []*Item
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			first = false
+			if header := headerColumns(record); header != nil {
+				cols = header
+				continue
+			}
+		}
+		if len(record) < len(cols) {
+			continue
+		}
+		item := &Item{}
+		for i, col := range cols {
+			col.set(item, record[i])
+		}
+		if item.id == "" {
+			item.id = itemID(item.url)
+		}
+		items = append(items, item)
+	}
+}
+
+// Разделитель csv - самый частый из ",", ";" и табуляции в первой строке
+func sniffDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	comma, max := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > max {
+			comma, max = d, n
+		}
+	}
+	return comma
+}
+
+// Колонки по строке с их названиями или nil, если это не строка названий
+func headerColumns(record []string) []*column {
+	var cols []*column
+	for _, title := range record {
+		col := columnByTitle(title)
+		if col == nil {
+			return nil
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Item struct {
+	id          string
+	header      string
+	description string
+	location    string
+	url         string
+	phone       string
+	price       string
+	date        string
+	params      []string
+	seller      string
+	seller_type string
+	rating      string
+	views       string
+	photos      []string
+
+	// Дата публикации, разобранная из date
+	published time.Time
+
+	// Пути к скачанным фотографиям (см. --photos-dir)
+	photo_paths []string
+
+	// Время, когда объявление было найдено
+	seen time.Time
+
+	// Кластер повторно выложенных объявлений (см. --dedupe)
+	cluster string
+
+	// Регион поиска, в котором найдено объявление
+	region string
+}
+
+// Ключ для сравнения объявлений: ID, а если его нет - адрес
+func (item *Item) key() string {
+	if item.id != "" {
+		return item.id
+	}
+	return item.url
+}
+
+// Представление объявления в JSON (одна строка в JSON Lines)
+type itemJSON struct {
+	ID          string     `json:"id"`
+	Header      string     `json:"header"`
+	Description string     `json:"description,omitempty"`
+	Price       string     `json:"price,omitempty"`
+	Phone       string     `json:"phone"`
+	Location    string     `json:"location"`
+	Date        string     `json:"date,omitempty"`
+	Published   *time.Time `json:"published,omitempty"`
+	URL         string     `json:"url"`
+	Params      []string   `json:"params,omitempty"`
+	Seller      string     `json:"seller,omitempty"`
+	SellerType  string     `json:"seller_type,omitempty"`
+	Rating      string     `json:"rating,omitempty"`
+	Views       string     `json:"views,omitempty"`
+	Photos      []string   `json:"photos,omitempty"`
+	PhotoPaths  []string   `json:"photo_paths,omitempty"`
+	Seen        *time.Time `json:"seen,omitempty"`
+	Cluster     string     `json:"cluster,omitempty"`
+	Region      string     `json:"region,omitempty"`
+}
+
+// Представление объявления с экспортируемыми полями (JSON, шаблоны)
+func (item *Item) view() *itemJSON {
+	j := itemJSON{
+		ID:          item.id,
+		Header:      item.header,
+		Description: item.description,
+		Price:       item.price,
+		Phone:       item.phone,
+		Location:    item.location,
+		Date:        item.date,
+		URL:         item.url,
+		Params:      item.params,
+		Seller:      item.seller,
+		SellerType:  item.seller_type,
+		Rating:      item.rating,
+		Views:       item.views,
+		Photos:      item.photos,
+		PhotoPaths:  item.photo_paths,
+		Cluster:     item.cluster,
+		Region:      item.region,
+	}
+	if !item.published.IsZero() {
+		j.Published = &item.published
+	}
+	if !item.seen.IsZero() {
+		j.Seen = &item.seen
+	}
+	return &j
+}
+
+func (item *Item) MarshalJSON() ([]byte, error) {
+	return json.Marshal(item.view())
+}
+
+func (item *Item) UnmarshalJSON(data []byte) error {
+	var j itemJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*item = Item{
+		id:          j.ID,
+		header:      j.Header,
+		description: j.Description,
+		price:       j.Price,
+		phone:       j.Phone,
+		location:    j.Location,
+		date:        j.Date,
+		url:         j.URL,
+		params:      j.Params,
+		seller:      j.Seller,
+		seller_type: j.SellerType,
+		rating:      j.Rating,
+		views:       j.Views,
+		photos:      j.Photos,
+		photo_paths: j.PhotoPaths,
+		cluster:     j.Cluster,
+		region:      j.Region,
+	}
+	if j.Published != nil {
+		item.published = *j.Published
+	}
+	if j.Seen != nil {
+		item.seen = *j.Seen
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Границы корзин гистограммы времени ответа (в секундах)
+var latency_buckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range latency_buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+type requestKey struct {
+	kind   string
+	status string
+}
+
+// Metrics собирает статистику работы парсера и отдаёт её
+// в текстовом формате Prometheus (см. --metrics-addr)
+type Metrics struct {
+	mu            sync.Mutex
+	requests      map[requestKey]uint64
+	latency       map[string]*histogram
+	bans          uint64
+	layout_errors uint64
+	items         uint64
+	throttle_wait float64
+}
+
+var metrics = NewMetrics()
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: make(map[requestKey]uint64),
+		latency:  make(map[string]*histogram),
+	}
+}
+
+// Учитывает запрос: kind - page, item, phone или photo,
+// status - HTTP код ответа или "error" при сетевой ошибке
+func (m *Metrics) ObserveRequest(kind, status string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{kind, status}]++
+	h, ok := m.latency[kind]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latency_buckets))}
+		m.latency[kind] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (m *Metrics) Ban() {
+	m.mu.Lock()
+	m.bans++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) LayoutError() {
+	m.mu.Lock()
+	m.layout_errors++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) ItemEmitted() {
+	m.mu.Lock()
+	m.items++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) ThrottleWait(d time.Duration) {
+	m.mu.Lock()
+	m.throttle_wait += d.Seconds()
+	m.mu.Unlock()
+}
+
+func (m *Metrics) Write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP selavito_requests_total Количество HTTP запросов по типу и статусу ответа.")
+	fmt.Fprintln(w, "# TYPE selavito_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Sort(requestKeys(keys))
+	for _, k := range keys {
+		fmt.Fprintf(w, "selavito_requests_total{type=%q,status=%q} %d\n", k.kind, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP selavito_request_duration_seconds Время выполнения HTTP запросов.")
+	fmt.Fprintln(w, "# TYPE selavito_request_duration_seconds histogram")
+	kinds := make([]string, 0, len(m.latency))
+	for kind := range m.latency {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		h := m.latency[kind]
+		for i, bound := range latency_buckets {
+			fmt.Fprintf(w, "selavito_request_duration_seconds_bucket{type=%q,le=\"%g\"} %d\n", kind, bound, h.counts[i])
+		}
+		fmt.Fprintf(w, "selavito_request_duration_seconds_bucket{type=%q,le=\"+Inf\"} %d\n", kind, h.count)
+		fmt.Fprintf(w, "selavito_request_duration_seconds_sum{type=%q} %g\n", kind, h.sum)
+		fmt.Fprintf(w, "selavito_request_duration_seconds_count{type=%q} %d\n", kind, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP selavito_bans_total Количество ответов, означающих бан IP.")
+	fmt.Fprintln(w, "# TYPE selavito_bans_total counter")
+	fmt.Fprintf(w, "selavito_bans_total %d\n", m.bans)
+
+	fmt.Fprintln(w, "# HELP selavito_layout_errors_total Количество страниц с неизвестной разметкой.")
+	fmt.Fprintln(w, "# TYPE selavito_layout_errors_total counter")
+	fmt.Fprintf(w, "selavito_layout_errors_total %d\n", m.layout_errors)
+
+	fmt.Fprintln(w, "# HELP selavito_items_emitted_total Количество сохранённых объявлений.")
+	fmt.Fprintln(w, "# TYPE selavito_items_emitted_total counter")
+	fmt.Fprintf(w, "selavito_items_emitted_total %d\n", m.items)
+
+	fmt.Fprintln(w, "# HELP selavito_throttle_wait_seconds_total Суммарное время ожидания между запросами.")
+	fmt.Fprintln(w, "# TYPE selavito_throttle_wait_seconds_total counter")
+	fmt.Fprintf(w, "selavito_throttle_wait_seconds_total %g\n", m.throttle_wait)
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.Write(w)
+}
+
+type requestKeys []requestKey
+
+func (k requestKeys) Len() int      { return len(k) }
+func (k requestKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k requestKeys) Less(i, j int) bool {
+	if k[i].kind != k[j].kind {
+		return k[i].kind < k[j].kind
+	}
+	return k[i].status < k[j].status
+}
+
+// Запускает HTTP сервер с метриками на заданном адресе
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	Info("Метрики доступны по адресу: http://%s/metrics", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			Error("Не удалось запустить сервер метрик: %s", err)
+		}
+	}()
+}
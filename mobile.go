@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+const BASE_URL string = "https://m.avito.ru"
+
+// Парсер мобильной версии сайта (m.avito.ru)
+type MobileParser struct{}
+
+func (p *MobileParser) SearchURL(location, category, query string) string {
+	return searchURL(BASE_URL, location, category, query)
+}
+
+func (p *MobileParser) ParsePage(doc *goquery.Document) (*Page, error) {
+	page := &Page{}
+
+	page.next_url = mobileNextURL(doc)
+
+	page.category = strings.TrimSpace(doc.Find(".nav-helper-header").First().Text())
+	if page.category == "" {
+		return nil, LayoutChanged
+	}
+	page.count = strings.TrimSpace(doc.Find(".nav-helper-text").First().Text())
+
+	page.items = parseMobileItems(doc)
+	return page, nil
+}
+
+func (p *MobileParser) ParseItem(doc *goquery.Document, item *Item) []string {
+	var phone_urls []string
+
+	item.location = strings.TrimSpace(doc.Find(".avito-address-text").First().Text())
+	item.description = strings.TrimSpace(doc.Find(".description-preview-wrapper").First().Text())
+	if seller := strings.TrimSpace(doc.Find(".person-name").First().Text()); seller != "" {
+		item.seller = seller
+	}
+	item.seller_type = sellerType(doc.Find(".person-info").First().Text())
+
+	item.photos = nil
+	doc.Find(".photo-self").Each(func(i int, s *goquery.Selection) {
+		src, exists := s.Attr("data-img-src")
+		if !exists {
+			src, exists = s.Attr("src")
+		}
+		if exists {
+			item.photos = append(item.photos, absoluteURL(BASE_URL, src))
+		}
+	})
+
+	doc.Find(".action-show-number").Each(func(i int, s *goquery.Selection) {
+		phone_url, exists := s.Attr("href")
+		if exists {
+			Debug("Found phone url: %s", phone_url)
+			phone_urls = append(phone_urls, strings.Join([]string{BASE_URL, phone_url, "?async"}, ""))
+		}
+	})
+	return phone_urls
+}
+
+func (p *MobileParser) SellerURL(doc *goquery.Document) string {
+	return sellerURL(BASE_URL, doc)
+}
+
+// Страница профиля продавца размечена так же, как результаты поиска
+func (p *MobileParser) ParseSellerPage(doc *goquery.Document) (*Page, error) {
+	seller := strings.TrimSpace(doc.Find(".person-name").First().Text())
+	if seller == "" {
+		return nil, LayoutChanged
+	}
+	page := &Page{next_url: mobileNextURL(doc), items: parseMobileItems(doc)}
+	for _, item := range page.items {
+		item.seller = seller
+	}
+	return page, nil
+}
+
+func mobileNextURL(doc *goquery.Document) string {
+	next_page_url, exists := doc.Find(".page-next").Find("a").First().Attr("href")
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("%s%s", BASE_URL, next_page_url)
+}
+
+// Объявления из списка (результаты поиска или профиль продавца)
+func parseMobileItems(doc *goquery.Document) []*Item {
+	var items []*Item
+	doc.Find(".b-item").Each(func(i int, s *goquery.Selection) {
+		item_url, exists := s.Find(".item-link").Attr("href")
+		if !exists {
+			Error(".item-link not found")
+			return
+		}
+		item := &Item{}
+		item.header = s.Find(".header-text").First().Text()
+		item.location = s.Find(".info-location").First().Text()
+		item.price = strings.TrimSpace(s.Find(".item-price").First().Text())
+		item.date = strings.TrimSpace(s.Find(".info-date").First().Text())
+		item.url = fmt.Sprintf("%s%s", BASE_URL, item_url)
+		item.id = itemID(item.url)
+		items = append(items, item)
+	})
+	return items
+}
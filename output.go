@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var non_digits = regexp.MustCompile(`\D`)
+
+// ItemWriter сохраняет объявления в файл определённого формата
+type ItemWriter interface {
+	Write(item *Item) error
+	Close() error
+}
+
+// Создаёт ItemWriter нужного формата (csv, jsonl, xlsx).
+// Если формат не указан, он определяется по расширению файла.
+// Путь "-" означает стандартный вывод.
+func NewItemWriter(path, format string) (ItemWriter, error) {
+	if format == "" {
+		format = formatByPath(path)
+	}
+	switch format {
+	case "csv":
+		return NewCSVWriter(path)
+	case "jsonl":
+		return NewJSONLinesWriter(path)
+	case "xlsx":
+		if path == "-" {
+			return nil, fmt.Errorf("Формат xlsx нельзя выводить в stdout")
+		}
+		return NewXLSXWriter(path)
+	}
+	return nil, fmt.Errorf("Неизвестный формат вывода: %s (допустимо: csv, jsonl, xlsx)", format)
+}
+
+func formatByPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".json", ".ndjson":
+		return "jsonl"
+	case ".xlsx":
+		return "xlsx"
+	}
+	return "csv"
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Открывает файл для записи, "-" - стандартный вывод
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if append_output {
+		return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	}
+	return os.Create(path)
+}
+
+// Настройки csv вывода (--columns, --csv-header, --delimiter, --quote, --bom)
+type CSVFormat struct {
+	columns   []string
+	header    string // "", ru или en
+	delimiter string
+	quote     string // minimal или all
+	bom       bool
+}
+
+var csv_format = CSVFormat{delimiter: ",", quote: "minimal"}
+
+const UTF8_BOM = "\xEF\xBB\xBF"
+
+func (f *CSVFormat) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.columns, "columns", nil,
+		"Колонки csv и xlsx через запятую, например id,header,price,phone,url (по умолчанию для csv header,location,phone,url)")
+	cmd.Flags().StringVar(&f.header, "csv-header", "",
+		"Строка с названиями колонок в начале csv: ru, en (по умолчанию без неё; в xlsx она есть всегда, по умолчанию ru)")
+	cmd.Flags().StringVar(&f.delimiter, "delimiter", ",",
+		"Разделитель колонок csv (\";\" для Excel с русской локалью, \"tab\" - табуляция)")
+	cmd.Flags().StringVar(&f.quote, "quote", "minimal",
+		"Кавычки в csv: minimal - только где нужно, all - вокруг каждого значения")
+	cmd.Flags().BoolVar(&f.bom, "bom", false,
+		"Добавлять в начало csv метку UTF-8 (BOM), чтобы Excel правильно показывал русский текст")
+}
+
+// Проверяет настройки и возвращает колонки и разделитель
+func (f *CSVFormat) parse() ([]*column, rune, error) {
+	cols := defaultColumns()
+	if len(f.columns) > 0 {
+		var err error
+		if cols, err = parseColumns(f.columns); err != nil {
+			return nil, 0, err
+		}
+	}
+	switch f.header {
+	case "", "ru", "en":
+	default:
+		return nil, 0, fmt.Errorf("Неизвестный язык заголовков csv: %s (допустимо: ru, en)", f.header)
+	}
+	switch f.quote {
+	case "minimal", "all":
+	default:
+		return nil, 0, fmt.Errorf("Неизвестный режим кавычек csv: %s (допустимо: minimal, all)", f.quote)
+	}
+
+	delimiter := f.delimiter
+	if delimiter == "tab" || delimiter == `\t` {
+		delimiter = "\t"
+	}
+	runes := []rune(delimiter)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' || runes[0] == utf8.RuneError {
+		return nil, 0, fmt.Errorf("Неверный разделитель csv: %q (нужен один символ)", f.delimiter)
+	}
+	return cols, runes[0], nil
+}
+
+// Записывает объявления в csv файл
+type CSVWriter struct {
+	file      io.WriteCloser
+	w         *csv.Writer
+	columns   []*column
+	comma     rune
+	quote_all bool
+}
+
+func NewCSVWriter(path string) (*CSVWriter, error) {
+	if _, _, err := csv_format.parse(); err != nil {
+		return nil, err
+	}
+	// При дописывании BOM и названия колонок уже есть в начале файла
+	preamble := !(append_output && nonEmptyFile(path))
+	f, err := openOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCSVWriter(f, preamble)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Создаёт CSVWriter с настройками csv_format.
+// С preamble в начало записываются BOM и названия колонок.
+func newCSVWriter(f io.WriteCloser, preamble bool) (*CSVWriter, error) {
+	cols, comma, err := csv_format.parse()
+	if err != nil {
+		return nil, err
+	}
+	c := &CSVWriter{file: f, w: csv.NewWriter(f), columns: cols, comma: comma, quote_all: csv_format.quote == "all"}
+	c.w.Comma = comma
+
+	if preamble && csv_format.bom {
+		if _, err := io.WriteString(f, UTF8_BOM); err != nil {
+			return nil, err
+		}
+	}
+	if preamble && csv_format.header != "" {
+		var titles []string
+		for _, col := range cols {
+			if csv_format.header == "ru" {
+				titles = append(titles, col.ru)
+			} else {
+				titles = append(titles, col.en)
+			}
+		}
+		if err := c.writeRecord(titles); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *CSVWriter) writeRecord(record []string) error {
+	if !c.quote_all {
+		if err := c.w.Write(record); err != nil {
+			return err
+		}
+		// Сбрасываем каждую запись, чтобы данные сразу уходили дальше по конвейеру
+		c.w.Flush()
+		return c.w.Error()
+	}
+
+	// csv.Writer не умеет заключать в кавычки все значения
+	fields := make([]string, len(record))
+	for i, field := range record {
+		fields[i] = `"` + strings.Replace(field, `"`, `""`, -1) + `"`
+	}
+	_, err := io.WriteString(c.file, strings.Join(fields, string(c.comma))+"\n")
+	return err
+}
+
+func (c *CSVWriter) Write(item *Item) error {
+	record := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		record[i] = col.get(item)
+	}
+	return c.writeRecord(record)
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	err := c.w.Error()
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Записывает объявления в формате JSON Lines (один JSON объект на строку)
+type JSONLinesWriter struct {
+	file io.WriteCloser
+	enc  *json.Encoder
+}
+
+func NewJSONLinesWriter(path string) (*JSONLinesWriter, error) {
+	f, err := openOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesWriter{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (j *JSONLinesWriter) Write(item *Item) error {
+	return j.enc.Encode(item)
+}
+
+func (j *JSONLinesWriter) Close() error {
+	return j.file.Close()
+}
+
+// Записывает объявления сразу в несколько файлов
+type multiWriter []ItemWriter
+
+func (m multiWriter) Write(item *Item) error {
+	var first error
+	for _, w := range m {
+		if err := w.Write(item); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiWriter) Close() error {
+	var first error
+	for _, w := range m {
+		if err := w.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Цена в рублях из строки вида "12 500 руб."
+func parsePrice(s string) (int64, bool) {
+	digits := non_digits.ReplaceAllString(s, "")
+	if digits == "" {
+		return 0, false
+	}
+	price, err := strconv.ParseInt(digits, 10, 64)
+	return price, err == nil
+}
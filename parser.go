@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"regexp"
+	"strings"
+)
+
+var item_id_re = regexp.MustCompile(`_(\d+)$`)
+var seller_url_re = regexp.MustCompile(`^(?:https?://[^/]+)?/user/[^/?#]+/profile`)
+
+var LayoutChanged error = errors.New("Неверный формат страницы! Скорее всего ваш IP забанили!")
+
+// Результат разбора страницы поиска
+type Page struct {
+	category string
+	count    string
+	next_url string
+	items    []*Item
+}
+
+// Parser знает разметку конкретной версии сайта avito.ru
+type Parser interface {
+	// Адрес страницы поиска
+	SearchURL(location, category, query string) string
+
+	// Разбирает страницу со списком объявлений
+	ParsePage(doc *goquery.Document) (*Page, error)
+
+	// Дополняет объявление данными со страницы объявления
+	// и возвращает ссылки для получения телефонного номера
+	ParseItem(doc *goquery.Document, item *Item) []string
+
+	// Ссылка на профиль продавца со страницы объявления
+	SellerURL(doc *goquery.Document) string
+
+	// Разбирает страницу с объявлениями продавца
+	ParseSellerPage(doc *goquery.Document) (*Page, error)
+}
+
+func NewParser(site_variant string) (Parser, error) {
+	switch site_variant {
+	case "mobile", "":
+		return &MobileParser{}, nil
+	case "desktop":
+		return &DesktopParser{}, nil
+	}
+	return nil, fmt.Errorf("Неизвестная версия сайта: %s (допустимо: mobile, desktop)", site_variant)
+}
+
+// Парсер для версии сайта, на которую указывает ссылка
+func parserForURL(url string) Parser {
+	if strings.HasPrefix(url, DESKTOP_BASE_URL) {
+		return &DesktopParser{}
+	}
+	return &MobileParser{}
+}
+
+func searchURL(base_url, location, category, query string) string {
+	if category == "" {
+		return fmt.Sprintf("%s/%s?q=%s", base_url, location, query)
+	}
+	return fmt.Sprintf("%s/%s/%s?q=%s", base_url, location, category, query)
+}
+
+// ID объявления - число в конце адреса (например .../kreslo_123456789)
+func itemID(item_url string) string {
+	if i := strings.IndexAny(item_url, "?#"); i >= 0 {
+		item_url = item_url[:i]
+	}
+	m := item_id_re.FindStringSubmatch(item_url)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// Приводит ссылку на картинку к абсолютному адресу
+func absoluteURL(base_url, src string) string {
+	switch {
+	case strings.HasPrefix(src, "//"):
+		return "https:" + src
+	case strings.HasPrefix(src, "/"):
+		return base_url + src
+	}
+	return src
+}
+
+// Ссылка вида /user/<id>/profile на странице объявления
+func sellerURL(base_url string, doc *goquery.Document) string {
+	href, _ := doc.Find("a[href*='/user/']").FilterFunction(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		return seller_url_re.MatchString(href)
+	}).First().Attr("href")
+	if href == "" {
+		return ""
+	}
+	return absoluteURL(base_url, seller_url_re.FindString(href))
+}
+
+// Является ли ссылка адресом профиля продавца (а не объявления)
+func isSellerURL(url string) bool {
+	return seller_url_re.MatchString(url)
+}
+
+// Тип продавца по подписи рядом с его именем: private (частное лицо) или company
+func sellerType(label string) string {
+	label = strings.ToLower(label)
+	switch {
+	case strings.Contains(label, "частное лицо"):
+		return "private"
+	case strings.Contains(label, "компания"), strings.Contains(label, "агентство"), strings.Contains(label, "магазин"):
+		return "company"
+	}
+	return ""
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	InitLoggers(false)
+}
+
+func loadDocument(t *testing.T, name string) *goquery.Document {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// Сравнивает поля объявления, которые заполняет парсер страницы со списком
+func checkListItems(t *testing.T, got, want []*Item) {
+	if len(got) != len(want) {
+		t.Fatalf("объявлений: %d, ожидалось %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		for _, f := range []struct{ name, got, want string }{
+			{"id", g.id, w.id},
+			{"url", g.url, w.url},
+			{"header", g.header, w.header},
+			{"price", g.price, w.price},
+			{"location", g.location, w.location},
+			{"date", g.date, w.date},
+			{"seller", g.seller, w.seller},
+		} {
+			if f.got != f.want {
+				t.Errorf("объявление %d, %s: %q, ожидалось %q", i, f.name, f.got, f.want)
+			}
+		}
+	}
+}
+
+func TestMobileParsePage(t *testing.T) {
+	page, err := (&MobileParser{}).ParsePage(loadDocument(t, "mobile_search.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.category != "Мебель и интерьер" {
+		t.Errorf("category: %q", page.category)
+	}
+	if page.count != "1 234 объявления" {
+		t.Errorf("count: %q", page.count)
+	}
+	if want := BASE_URL + "/moskva/mebel_i_interer?p=2&q=%D0%BA%D1%80%D0%B5%D1%81%D0%BB%D0%BE"; page.next_url != want {
+		t.Errorf("next_url: %q, ожидалось %q", page.next_url, want)
+	}
+	checkListItems(t, page.items, []*Item{
+		{
+			id:       "123456789",
+			url:      BASE_URL + "/moskva/mebel_i_interer/kreslo_ofisnoe_123456789",
+			header:   "Кресло офисное",
+			price:    "5 000 руб.",
+			location: "Москва, м. Сокол",
+			date:     "Сегодня, 12:30",
+		},
+		{
+			id:       "987654321",
+			url:      BASE_URL + "/moskva/mebel_i_interer/kreslo-kachalka_987654321",
+			header:   "Кресло-качалка",
+			price:    "12 000 руб.",
+			location: "Москва, м. Динамо",
+			date:     "Вчера, 18:05",
+		},
+	})
+}
+
+func TestMobileParseItem(t *testing.T) {
+	p := &MobileParser{}
+	doc := loadDocument(t, "mobile_item.html")
+	item := &Item{location: "Москва"}
+	phone_urls := p.ParseItem(doc, item)
+
+	want_phones := []string{BASE_URL + "/moskva/mebel_i_interer/kreslo_ofisnoe_123456789/phone/3f2e1d?async"}
+	if !reflect.DeepEqual(phone_urls, want_phones) {
+		t.Errorf("phone_urls: %q, ожидалось %q", phone_urls, want_phones)
+	}
+	if item.location != "Москва, Ленинградский проспект, 80" {
+		t.Errorf("location: %q", item.location)
+	}
+	if item.description != "Удобное кресло, почти не пользовались.\n      Самовывоз от метро Сокол." {
+		t.Errorf("description: %q", item.description)
+	}
+	if item.seller != "Иван" || item.seller_type != "private" {
+		t.Errorf("seller: %q, seller_type: %q", item.seller, item.seller_type)
+	}
+	want_photos := []string{
+		"https://00.img.avito.st/640x480/1111111111.jpg",
+		"https://01.img.avito.st/640x480/2222222222.jpg",
+	}
+	if !reflect.DeepEqual(item.photos, want_photos) {
+		t.Errorf("photos: %q, ожидалось %q", item.photos, want_photos)
+	}
+	if url := p.SellerURL(doc); url != BASE_URL+"/user/a1b2c3d4e5f6/profile" {
+		t.Errorf("SellerURL: %q", url)
+	}
+}
+
+func TestMobileParseSellerPage(t *testing.T) {
+	page, err := (&MobileParser{}).ParseSellerPage(loadDocument(t, "mobile_seller.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.next_url != BASE_URL+"/user/a1b2c3d4e5f6/profile?p=2" {
+		t.Errorf("next_url: %q", page.next_url)
+	}
+	checkListItems(t, page.items, []*Item{
+		{
+			id:       "123456789",
+			url:      BASE_URL + "/moskva/mebel_i_interer/kreslo_ofisnoe_123456789",
+			header:   "Кресло офисное",
+			price:    "5 000 руб.",
+			location: "Москва",
+			date:     "Сегодня, 12:30",
+			seller:   "Иван",
+		},
+		{
+			id:       "555666777",
+			url:      BASE_URL + "/moskva/mebel_i_interer/stol_pismennyy_555666777",
+			header:   "Стол письменный",
+			price:    "3 500 руб.",
+			location: "Москва",
+			date:     "2 октября",
+			seller:   "Иван",
+		},
+	})
+}
+
+func TestDesktopParsePage(t *testing.T) {
+	page, err := (&DesktopParser{}).ParsePage(loadDocument(t, "desktop_search.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.category != "Мебель и интерьер в Москве" {
+		t.Errorf("category: %q", page.category)
+	}
+	if page.count != "1 234" {
+		t.Errorf("count: %q", page.count)
+	}
+	if want := DESKTOP_BASE_URL + "/moskva/mebel_i_interer?p=2&q=%D0%BA%D1%80%D0%B5%D1%81%D0%BB%D0%BE"; page.next_url != want {
+		t.Errorf("next_url: %q, ожидалось %q", page.next_url, want)
+	}
+	checkListItems(t, page.items, []*Item{
+		{
+			id:       "123456789",
+			url:      DESKTOP_BASE_URL + "/moskva/mebel_i_interer/kreslo_ofisnoe_123456789",
+			header:   "Кресло офисное",
+			price:    "5 000 руб.",
+			location: "м. Сокол, Москва",
+			date:     "Сегодня 12:30",
+		},
+		{
+			id:       "987654321",
+			url:      DESKTOP_BASE_URL + "/moskva/mebel_i_interer/kreslo-kachalka_987654321",
+			header:   "Кресло-качалка",
+			price:    "12 000 руб.",
+			location: "Москва",
+			date:     "Вчера 18:05",
+		},
+	})
+}
+
+func TestDesktopParseItem(t *testing.T) {
+	p := &DesktopParser{}
+	doc := loadDocument(t, "desktop_item.html")
+	item := &Item{location: "Москва", price: "5 000 руб."}
+	phone_urls := p.ParseItem(doc, item)
+
+	want_phones := []string{DESKTOP_BASE_URL + "/items/phone/123456789?pkey=3f2e1d&vsrc=r"}
+	if !reflect.DeepEqual(phone_urls, want_phones) {
+		t.Errorf("phone_urls: %q, ожидалось %q", phone_urls, want_phones)
+	}
+	for _, f := range []struct{ name, got, want string }{
+		{"location", item.location, "Москва, Ленинградский проспект, 80"},
+		{"price", item.price, "5 000 ₽"},
+		{"description", item.description, "Удобное кресло, почти не пользовались."},
+		{"seller", item.seller, "Иван"},
+		{"seller_type", item.seller_type, "private"},
+		{"rating", item.rating, "4,8"},
+		{"views", item.views, "245 (+12)"},
+	} {
+		if f.got != f.want {
+			t.Errorf("%s: %q, ожидалось %q", f.name, f.got, f.want)
+		}
+	}
+	want_params := []string{"Вид мебели: Кресла", "Состояние: Б/у"}
+	if !reflect.DeepEqual(item.params, want_params) {
+		t.Errorf("params: %q, ожидалось %q", item.params, want_params)
+	}
+	want_photos := []string{
+		"https://00.img.avito.st/640x480/1111111111.jpg",
+		"https://01.img.avito.st/640x480/2222222222.jpg",
+	}
+	if !reflect.DeepEqual(item.photos, want_photos) {
+		t.Errorf("photos: %q, ожидалось %q", item.photos, want_photos)
+	}
+	if url := p.SellerURL(doc); url != DESKTOP_BASE_URL+"/user/a1b2c3d4e5f6/profile" {
+		t.Errorf("SellerURL: %q", url)
+	}
+}
+
+func TestDesktopParseSellerPage(t *testing.T) {
+	page, err := (&DesktopParser{}).ParseSellerPage(loadDocument(t, "desktop_seller.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.count != "2" {
+		t.Errorf("count: %q", page.count)
+	}
+	if page.next_url != DESKTOP_BASE_URL+"/user/a1b2c3d4e5f6/profile?p=2" {
+		t.Errorf("next_url: %q", page.next_url)
+	}
+	checkListItems(t, page.items, []*Item{
+		{
+			id:       "123456789",
+			url:      DESKTOP_BASE_URL + "/moskva/mebel_i_interer/kreslo_ofisnoe_123456789",
+			header:   "Кресло офисное",
+			price:    "5 000 ₽",
+			location: "Москва, м. Сокол",
+			date:     "Сегодня 12:30",
+			seller:   "Иван",
+		},
+		{
+			id:       "555666777",
+			url:      DESKTOP_BASE_URL + "/moskva/mebel_i_interer/stol_pismennyy_555666777",
+			header:   "Стол письменный",
+			price:    "3 500 ₽",
+			location: "Москва",
+			date:     "2 октября",
+			seller:   "Иван",
+		},
+	})
+}
+
+func TestLayoutChanged(t *testing.T) {
+	for _, p := range []Parser{&MobileParser{}, &DesktopParser{}} {
+		doc := loadDocument(t, "unknown.html")
+		if _, err := p.ParsePage(doc); err != LayoutChanged {
+			t.Errorf("%T.ParsePage: %v, ожидалось LayoutChanged", p, err)
+		}
+		if _, err := p.ParseSellerPage(doc); err != LayoutChanged {
+			t.Errorf("%T.ParseSellerPage: %v, ожидалось LayoutChanged", p, err)
+		}
+		if url := p.SellerURL(doc); url != "" {
+			t.Errorf("%T.SellerURL: %q, ожидалась пустая строка", p, url)
+		}
+	}
+}
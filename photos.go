@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Каталог для сохранения фотографий (пустая строка - не сохранять)
+var photos_dir string
+
+// Скачивает все фотографии объявления в <photos_dir>/<item_id>/N.jpg.
+// Уже скачанные файлы повторно не загружаются.
+func downloadPhotos(item *Item) {
+	if photos_dir == "" || len(item.photos) == 0 {
+		return
+	}
+
+	id := item.id
+	if id == "" {
+		Error("Не удалось определить ID объявления, фотографии не сохранены: %s", item.url)
+		return
+	}
+
+	dir := filepath.Join(photos_dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		Error("%s", err.Error())
+		return
+	}
+
+	item.photo_paths = nil
+	for i, photo_url := range item.photos {
+		path := filepath.Join(dir, fmt.Sprintf("%d.jpg", i+1))
+		if _, err := os.Stat(path); err == nil {
+			Debug("Photo already exists: %s", path)
+			item.photo_paths = append(item.photo_paths, path)
+			continue
+		}
+		if err := downloadFile(photo_url, item.url, path); err != nil {
+			Error("Не удалось скачать фото %s: %s", photo_url, err)
+			continue
+		}
+		item.photo_paths = append(item.photo_paths, path)
+	}
+}
+
+func downloadFile(file_url, referer, path string) error {
+	Debug("Downloading %s to %s", file_url, path)
+
+	req, err := newRequest(file_url, referer)
+	if err != nil {
+		return err
+	}
+
+	throttleWait()
+	res, err := doRequest("photo", req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 403 {
+		return IPBanned
+	}
+	if res.StatusCode != 200 {
+		return fmt.Errorf("HTTP %d", res.StatusCode)
+	}
+
+	// Пишем во временный файл, чтобы не оставлять недокачанных фотографий
+	tmp_path := path + ".part"
+	f, err := os.Create(tmp_path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, res.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp_path)
+		return err
+	}
+	return os.Rename(tmp_path, path)
+}
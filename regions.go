@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Поиск идёт сразу по нескольким регионам (в результатах нужна колонка с регионом)
+var multi_region bool
+
+// Читает список регионов из файла: по одному на строке, # - комментарий
+func readLocationsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var locations []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			locations = append(locations, line)
+		}
+	}
+	return locations, scanner.Err()
+}
+
+// Отбрасывает объявления, уже найденные в другом регионе
+// (например, в moskva и moskovskaya_oblast), ещё до загрузки объявления
+type duplicateRule struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newDuplicateRule() *duplicateRule {
+	return &duplicateRule{seen: make(map[string]bool)}
+}
+
+func (r *duplicateRule) Name() string  { return "duplicate" }
+func (r *duplicateRule) Listing() bool { return true }
+
+func (r *duplicateRule) Match(item *Item) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[item.key()] {
+		return false
+	}
+	r.seen[item.key()] = true
+	return true
+}
+
+// Выполняет один и тот же поиск одновременно по всем регионам.
+// Пауза между запросами (--pause) общая для всех регионов,
+// search.MaxItems ограничивает количество объявлений в каждом регионе.
+func crawlRegions(parser Parser, search Search, locations []string, items chan<- *Item, stop <-chan struct{}) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(locations))
+	for i, location := range locations {
+		wg.Add(1)
+		go func(i int, location string) {
+			defer wg.Done()
+			s := search
+			s.Location = location
+			if errs[i] = crawl(parser, s, items, stop, nil); errs[i] != nil && len(locations) > 1 {
+				Error("%s: %s", location, errs[i])
+			}
+		}(i, location)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule определяет, когда запускать сохранённый поиск
+type Schedule interface {
+	// Время первого запуска, если поиск ещё ни разу не запускался
+	First(now time.Time) time.Time
+
+	// Время следующего запуска после предыдущего
+	Next(after time.Time) time.Time
+}
+
+var every_re = regexp.MustCompile(`^every\s+(\S+)(?:\s+between\s+(\d{1,2}:\d{2})\s+and\s+(\d{1,2}:\d{2}))?$`)
+
+// Разбирает расписание в одном из форматов:
+//
+//	"every 15m", "every 1h between 08:00 and 23:00" - с заданным интервалом
+//	"*/15 8-22 * * 1-5" - cron (минуты, часы, дни месяца, месяцы, дни недели)
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(strings.ToLower(expr))
+	if strings.HasPrefix(expr, "every") {
+		return parseEvery(expr)
+	}
+	return parseCron(expr)
+}
+
+// Запуск с заданным интервалом, опционально только в заданное время суток
+type everySchedule struct {
+	interval time.Duration
+	window   bool
+	from     time.Duration
+	to       time.Duration
+}
+
+func parseEvery(expr string) (Schedule, error) {
+	m := every_re.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("Неверное расписание: %s (пример: every 15m between 08:00 and 23:00)", expr)
+	}
+	interval, err := time.ParseDuration(m[1])
+	if err != nil || interval < time.Minute {
+		return nil, fmt.Errorf("Неверный интервал: %s (не меньше 1m)", m[1])
+	}
+	s := &everySchedule{interval: interval}
+	if m[2] != "" {
+		if s.from, err = parseClock(m[2]); err != nil {
+			return nil, err
+		}
+		if s.to, err = parseClock(m[3]); err != nil {
+			return nil, err
+		}
+		s.window = true
+	}
+	return s, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	hour, _ := strconv.Atoi(parts[0])
+	minute, _ := strconv.Atoi(parts[1])
+	if hour > 24 || minute > 59 || (hour == 24 && minute > 0) {
+		return 0, fmt.Errorf("Неверное время: %s", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+func (s *everySchedule) inWindow(t time.Time) bool {
+	if !s.window {
+		return true
+	}
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if s.from <= s.to {
+		return tod >= s.from && tod <= s.to
+	}
+	// Интервал через полночь, например 22:00 - 06:00
+	return tod >= s.from || tod <= s.to
+}
+
+// Ближайшее к t время, попадающее в разрешённый интервал суток
+func (s *everySchedule) fit(t time.Time) time.Time {
+	if s.inWindow(t) {
+		return t
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(s.from)
+	if !start.After(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+func (s *everySchedule) First(now time.Time) time.Time {
+	return s.fit(now)
+}
+
+func (s *everySchedule) Next(after time.Time) time.Time {
+	return s.fit(after.Add(s.interval))
+}
+
+// Расписание в формате cron
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	dom_any, dow_any              bool
+}
+
+func parseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Неверное расписание: %s (ожидается 5 полей cron или every ...)", expr)
+	}
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 - тоже воскресенье
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.dom_any = fields[2] == "*"
+	s.dow_any = fields[4] == "*"
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("Неверный шаг в поле cron: %s", field)
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("Неверное поле cron: %s", field)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("Неверное поле cron: %s", field)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("Значение вне диапазона %d-%d в поле cron: %s", min, max, field)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *cronSchedule) match(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom := s.dom[t.Day()]
+	dow := s.dow[int(t.Weekday())]
+	// Как в cron: если заданы и день месяца, и день недели - достаточно совпадения одного
+	switch {
+	case s.dom_any && s.dow_any:
+		return true
+	case s.dom_any:
+		return dow
+	case s.dow_any:
+		return dom
+	}
+	return dom || dow
+}
+
+func (s *cronSchedule) First(now time.Time) time.Time {
+	return s.Next(now.Truncate(time.Minute).Add(-time.Second))
+}
+
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Перебираем минуты не дальше, чем на 5 лет вперёд (29 февраля)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if s.match(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
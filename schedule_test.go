@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Время в тестах расписаний: 19 октября 2026, понедельник
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestScheduleNext(t *testing.T) {
+	for _, c := range []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		// Интервал
+		{"every 15m", at(19, 10, 0), at(19, 10, 15)},
+		{"every 1h between 08:00 and 23:00", at(19, 12, 30), at(19, 13, 30)},
+		{"every 1h between 08:00 and 23:00", at(19, 22, 30), at(20, 8, 0)},
+		// Окно через полночь
+		{"every 2h between 22:00 and 06:00", at(19, 23, 0), at(20, 1, 0)},
+		{"every 2h between 22:00 and 06:00", at(20, 5, 0), at(20, 22, 0)},
+		{"every 30m between 22:00 and 06:00", at(19, 21, 50), at(19, 22, 20)},
+
+		// Cron: шаги и диапазоны
+		{"*/15 * * * *", at(19, 10, 7), at(19, 10, 15)},
+		{"*/15 * * * *", at(19, 10, 45), at(19, 11, 0)},
+		{"5-10/5 * * * *", at(19, 10, 6), at(19, 10, 10)},
+		{"0 8-22 * * *", at(19, 22, 0), at(20, 8, 0)},
+		{"30 9 * * 1-5", at(23, 10, 0), at(26, 9, 30)}, // пятница -> понедельник
+		{"0 12 * * 0", at(19, 13, 0), at(25, 12, 0)},
+		{"0 12 * * 7", at(19, 13, 0), at(25, 12, 0)}, // 7 - тоже воскресенье
+		{"0 0 1,15 * *", at(2, 0, 0), at(15, 0, 0)},
+		{"0 0 1 1 *", at(19, 0, 0), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+
+		// День месяца ИЛИ день недели
+		{"0 10 25 * 3", at(19, 11, 0), at(21, 10, 0)}, // среда раньше 25-го
+		{"0 10 20 * 6", at(19, 11, 0), at(20, 10, 0)}, // 20-е раньше субботы
+	} {
+		s, err := ParseSchedule(c.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %s", c.expr, err)
+			continue
+		}
+		if got := s.Next(c.after); !got.Equal(c.want) {
+			t.Errorf("%q: Next(%s) = %s, ожидалось %s", c.expr, c.after, got, c.want)
+		}
+	}
+}
+
+func TestScheduleFirst(t *testing.T) {
+	for _, c := range []struct {
+		expr string
+		now  time.Time
+		want time.Time
+	}{
+		{"every 15m", at(19, 10, 7), at(19, 10, 7)},
+		{"every 1h between 08:00 and 23:00", at(19, 5, 0), at(19, 8, 0)},
+		{"every 1h between 22:00 and 06:00", at(19, 3, 0), at(19, 3, 0)},
+		{"*/15 * * * *", at(19, 10, 15), at(19, 10, 15)},
+		{"*/15 * * * *", at(19, 10, 16), at(19, 10, 30)},
+	} {
+		s, err := ParseSchedule(c.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %s", c.expr, err)
+			continue
+		}
+		if got := s.First(c.now); !got.Equal(c.want) {
+			t.Errorf("%q: First(%s) = %s, ожидалось %s", c.expr, c.now, got, c.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"every",
+		"every 10s",
+		"every 1h between 25:00 and 06:00",
+		"every 1h between 08:00",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q): ожидалась ошибка", expr)
+		}
+	}
+}
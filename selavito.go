@@ -1,35 +1,24 @@
 package main
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/PuerkitoBio/goquery"
 	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/fatih/color"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/mattn/go-isatty"
 	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
 	"io"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"strings"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
-const BASE_URL string = "https://m.avito.ru"
-
 var IPBanned error = errors.New("Ваш IP забанили!!")
 var throttle <-chan time.Time
 
-type Item struct {
-	header   string
-	location string
-	url      string
-	phone    string
-}
-
 var (
 	DebugLogger *log.Logger
 	InfoLogger  *log.Logger
@@ -52,12 +41,13 @@ func InitLoggers(verbose bool) {
 	var infoHandle, errorHandle, debugHandle io.Writer
 
 	if verbose {
-		debugHandle = os.Stdout
+		debugHandle = os.Stderr
 	} else {
 		debugHandle = ioutil.Discard
 	}
 
-	infoHandle = os.Stdout
+	// Весь вывод для человека идёт в stderr, stdout остаётся для данных (--output -)
+	infoHandle = os.Stderr
 	errorHandle = os.Stderr
 
 	DebugLogger = log.New(debugHandle, "", 0)
@@ -65,23 +55,41 @@ func InitLoggers(verbose bool) {
 	ErrorLogger = log.New(errorHandle, "", 0)
 }
 
-// Достаёт телефонный номер из JSON по заданному URL
+// Достаёт телефонный номер из JSON по заданному URL.
+// Если сайт показал капчу, запрос повторяется после разблокировки.
 func getPhone(phone_url, referer string) (string, error) {
+	for {
+		generation := unblockGeneration()
+		phone, err := requestPhone(phone_url, referer)
+		if err == Blocked && waitUnblock(referer, generation) {
+			continue
+		}
+		return phone, err
+	}
+}
+
+func requestPhone(phone_url, referer string) (string, error) {
 	Debug("Persing phone url: %s", phone_url)
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", phone_url, nil)
-	req.Header.Add("referer", referer)
+	req, err := newRequest(phone_url, referer)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
 
 	throttleWait()
-	res, err := client.Do(req)
+	res, err := doRequest("phone", req)
 	if err != nil {
 		return "", err
 	}
 
 	defer res.Body.Close()
 
-	body, err := ioutil.ReadAll(res.Body)
+	decoded, err := decodeBody(res.Body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(decoded)
 	if err != nil {
 		Error("%s", err.Error())
 		return "", err
@@ -89,9 +97,17 @@ func getPhone(phone_url, referer string) (string, error) {
 	if res.StatusCode == 403 {
 		return "", IPBanned
 	}
+	if res.StatusCode == 429 {
+		metrics.Ban()
+		return "", Blocked
+	}
 
 	phone_data := make(map[string]string)
 	err = json.Unmarshal(body, &phone_data)
+	if err != nil && blockedBody(body) {
+		metrics.Ban()
+		return "", Blocked
+	}
 	if err != nil {
 		Error("%s", err.Error())
 		return "", err
@@ -101,62 +117,75 @@ func getPhone(phone_url, referer string) (string, error) {
 	return phone_data["phone"], nil
 }
 
-func parseItem(item *Item, wg *sync.WaitGroup, items chan *Item) {
+// Загружает страницу объявления, дополняет объявление данными с неё
+// и возвращает ссылки для получения телефонного номера.
+// Уже известные время обнаружения и дата публикации (например, у объявлений
+// из файла для fill-phones) не меняются: относительная дата вроде "сегодня"
+// отсчитывается от момента, когда объявление нашли.
+func fetchItem(parser Parser, item *Item) ([]string, error) {
 	throttleWait()
 
-	doc, err := goquery.NewDocument(item.url)
+	doc, err := fetchDocument("item", item.url, "")
 	if err != nil {
-		Error("%s", err.Error())
-		wg.Done()
-		return
+		return nil, err
 	}
 
-	item.location = doc.Find(".avito-address-text").First().Text()
-	item.location = strings.TrimSpace(item.location)
-
-	doc.Find(".action-show-number").Each(func(i int, s *goquery.Selection) {
-		phone_url, exists := s.Attr("href")
-		if exists {
-			Debug("Found phone url: %s", phone_url)
-
-			phone_url := strings.Join([]string{BASE_URL, phone_url, "?async"}, "")
-			item.phone, err = getPhone(phone_url, item.url)
-			if err != nil {
-				Error("%s", err.Error())
-			} else {
-				items <- item
-			}
-		}
-	})
-	wg.Done()
+	phone_urls := parser.ParseItem(doc, item)
+	if item.seen.IsZero() {
+		item.seen = time.Now()
+	}
+	if item.published.IsZero() {
+		item.published, _ = parseDate(item.date, item.seen)
+	}
+	return phone_urls, nil
 }
 
-func saveToCSV(path_to_csvfile string, items chan *Item, wg *sync.WaitGroup) {
-	csvfile, err := os.Create(path_to_csvfile)
+// На каждый номер отдельная запись
+func emitPhones(item *Item, phones []string, items chan<- *Item) {
+	for _, phone := range phones {
+		found := *item
+		found.phone = phone
+		items <- &found
+	}
+}
 
+// Обрабатывает объявление со страницы поиска: загружает его страницу и телефоны.
+// Возвращает ошибку, только если обход нужно остановить (см. fatalError).
+func parseItem(parser Parser, item *Item, items chan<- *Item) error {
+	phone_urls, err := fetchItem(parser, item)
 	if err != nil {
+		itemFailed(err)
+		if fatalError(err) {
+			return err
+		}
 		Error("%s", err.Error())
-		return
+		return nil
 	}
-	defer csvfile.Close()
-
-	w := csv.NewWriter(csvfile)
-
-	for item := range items {
-		record := []string{item.header, item.location, item.phone, item.url}
-		if err := w.Write(record); err != nil {
-			Error("Не удалось записать в csv файл: %s", err)
-		}
+	if !item_filter.Match(item) {
+		return nil
 	}
-
-	// Write any buffered data to the underlying writer (standard output).
-	w.Flush()
-
-	if err := w.Error(); err != nil {
-		Error("%s", err.Error())
+	downloadPhotos(item)
+	if no_phones {
+		items <- item
+		return nil
 	}
 
-	wg.Done()
+	var phones []string
+	for _, phone_url := range phone_urls {
+		phone, err := getPhone(phone_url, item.url)
+		if err != nil {
+			itemFailed(err)
+			if fatalError(err) {
+				emitPhones(item, phones, items)
+				return err
+			}
+			Error("%s", err.Error())
+			continue
+		}
+		phones = append(phones, phone)
+	}
+	emitPhones(item, phones, items)
+	return nil
 }
 
 func throttleSet(pause int64) {
@@ -172,132 +201,364 @@ func throttleWait() {
 		return
 	}
 	Debug("Waiting throttle...")
+	start := time.Now()
 	<-throttle
+	metrics.ThrottleWait(time.Since(start))
 	Debug("Waiting throttle done")
 }
 
+// Общие параметры для всех команд
+type Options struct {
+	verbose        bool
+	pause          int64
+	site_variant   string
+	cookie_jar     string
+	cookies_import string
+	metrics_addr   string
+	store          string
+
+	// Завершаться с ошибкой, если часть объявлений не обработана
+	fail_on_partial bool
+
+	// Ждать разблокировки от пользователя, если сайт показал капчу
+	interactive bool
+}
+
+var options Options
+
+// Подготавливает всё необходимое для работы с сайтом: логи, cookies,
+// метрики и паузу между запросами. Возвращаемую функцию нужно вызвать
+// по завершении работы, чтобы сохранить cookies.
+func startSession() (func(), error) {
+	InitLoggers(options.verbose)
+
+	jar, err := NewCookieJar(options.cookie_jar)
+	if err != nil {
+		return nil, err
+	}
+	if options.cookies_import != "" {
+		if err := jar.Import(options.cookies_import); err != nil {
+			return nil, fmt.Errorf("Не удалось загрузить cookies: %s", err)
+		}
+	}
+	initClient(jar)
+
+	if options.metrics_addr != "" {
+		serveMetrics(options.metrics_addr)
+	}
+
+	throttleSet(options.pause)
+
+	return func() {
+		if err := jar.Save(); err != nil {
+			Error("Не удалось сохранить cookies: %s", err)
+		}
+	}, nil
+}
+
+// Параметры сохранения результатов, общие для команд поиска
+type OutputOptions struct {
+	output           string
+	format           string
+	csv              string
+	xlsx             string
+	dedupe_threshold float64
+	dedupe_photos    bool
+	one_per_cluster  bool
+
+	template        string
+	format_template string
+	template_header string
+	template_footer string
+
+	exec             string
+	exec_policy      string
+	exec_retries     int
+	exec_concurrency int
+
+	// Закрывается, когда обход нужно остановить (--exec-on-error abort)
+	stop      chan struct{}
+	stop_once sync.Once
+}
+
+func (o *OutputOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.output, "output", "o", "",
+		"Путь к файлу для сохранения данных (\"-\" - вывод в stdout)")
+	cmd.Flags().StringVarP(&o.format, "format", "f", "",
+		"Формат вывода: csv, jsonl, xlsx (по умолчанию определяется по расширению файла)")
+	cmd.Flags().StringVar(&o.csv, "csv", "",
+		"Путь к csv файлу для сохранения данных")
+	cmd.Flags().StringVar(&o.xlsx, "xlsx", "",
+		"Путь к xlsx файлу для сохранения данных (для Excel)")
+	cmd.Flags().BoolVar(&append_output, "append", false,
+		"Дописывать в существующие csv и jsonl файлы только новые объявления (по ID и ссылке)")
+	csv_format.AddFlags(cmd)
+	cmd.Flags().StringVar(&photos_dir, "photos-dir", "",
+		"Каталог для сохранения фотографий объявлений (<каталог>/<id объявления>/N.jpg)")
+	cmd.Flags().BoolVar(&dedupe, "dedupe", false,
+		"Объединять повторно выложенные объявления в кластеры (по телефону и похожему тексту)")
+	cmd.Flags().Float64Var(&o.dedupe_threshold, "dedupe-threshold", 0.8,
+		"Минимальная похожесть заголовка и описания для дубликатов (от 0 до 1)")
+	cmd.Flags().BoolVar(&o.dedupe_photos, "dedupe-photos", false,
+		"Дополнительно сравнивать первые фотографии объявлений")
+	cmd.Flags().BoolVar(&o.one_per_cluster, "one-per-cluster", false,
+		"Сохранять только одно объявление из каждого кластера (включает --dedupe)")
+	cmd.Flags().StringVar(&o.template, "template", "",
+		"Файл с шаблоном text/template для вывода каждого объявления в --output (по умолчанию в stdout)")
+	cmd.Flags().StringVar(&o.format_template, "format-template", "",
+		"Шаблон для вывода каждого объявления строкой (пример: '{{.Phone}};{{csv .Header}}')")
+	cmd.Flags().StringVar(&o.template_header, "template-header", "",
+		"Шаблон начала файла для --template и --format-template")
+	cmd.Flags().StringVar(&o.template_footer, "template-footer", "",
+		"Шаблон конца файла для --template и --format-template ({{.Count}} - количество объявлений)")
+	cmd.Flags().StringVar(&o.exec, "exec", "",
+		"Команда, которой передаётся каждое объявление в формате JSON на stdin")
+	cmd.Flags().StringVar(&o.exec_policy, "exec-on-error", EXEC_SKIP,
+		"Что делать, если команда завершилась с ошибкой: skip, retry, abort")
+	cmd.Flags().IntVar(&o.exec_retries, "exec-retries", 3,
+		"Количество повторов команды для --exec-on-error retry")
+	cmd.Flags().IntVar(&o.exec_concurrency, "exec-concurrency", 1,
+		"Сколько команд --exec может выполняться одновременно")
+}
+
+// Указан ли хотя бы один файл для сохранения результатов
+func (o *OutputOptions) Empty() bool {
+	return o.output == "" && o.csv == "" && o.xlsx == "" && o.exec == "" && !o.templated()
+}
+
+// Вывод в --output по шаблону вместо --format
+func (o *OutputOptions) templated() bool {
+	return o.template != "" || o.format_template != ""
+}
+
+// Пишется ли что-то из результатов в stdout
+func (o *OutputOptions) stdout() bool {
+	return o.output == "-" || o.csv == "-" || (o.templated() && o.output == "")
+}
+
+// Канал, который закрывается, когда обход нужно остановить
+func (o *OutputOptions) Stop() <-chan struct{} {
+	return o.stop
+}
+
+// Открывает все указанные файлы (и локальное хранилище) для записи
+func (o *OutputOptions) Open() (ItemWriter, error) {
+	if o.one_per_cluster {
+		dedupe = true
+	}
+	if _, _, err := csv_format.parse(); err != nil {
+		return nil, err
+	}
+	// Вывод команды --exec идёт в stdout и смешался бы с результатами
+	if o.exec != "" && o.stdout() {
+		return nil, fmt.Errorf("--exec нельзя совмещать с выводом результатов в stdout (-o -, --csv - или шаблон без -o)")
+	}
+	if append_output && (o.templated() || o.xlsx != "" || o.format == "xlsx" || (o.format == "" && formatByPath(o.output) == "xlsx")) {
+		return nil, fmt.Errorf("--append поддерживается только для csv и jsonl")
+	}
+
+	var writers multiWriter
+	var appended []*AppendWriter
+	o.stop = make(chan struct{})
+	if o.exec != "" {
+		w, err := NewExecWriter(o.exec, o.exec_policy, o.exec_retries, o.exec_concurrency, func() {
+			o.stop_once.Do(func() { close(o.stop) })
+		})
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, w)
+	}
+
+	if o.templated() {
+		tmpl, err := parseItemTemplate(o.template, o.format_template, o.template_header, o.template_footer)
+		if err != nil {
+			return nil, err
+		}
+		path := o.output
+		if path == "" {
+			path = "-"
+		}
+		w, err := NewTemplateWriter(path, tmpl)
+		if err != nil {
+			return nil, &OutputError{err}
+		}
+		writers = append(writers, w)
+	} else if o.output != "" {
+		open := NewItemWriter
+		if append_output {
+			open = openAppend
+		}
+		w, err := open(o.output, o.format)
+		if err != nil {
+			return nil, &OutputError{err}
+		}
+		if a, ok := w.(*AppendWriter); ok {
+			appended = append(appended, a)
+		}
+		writers = append(writers, w)
+	}
+	if o.csv != "" {
+		var w ItemWriter
+		var err error
+		if append_output {
+			w, err = openAppend(o.csv, "csv")
+		} else {
+			w, err = NewCSVWriter(o.csv)
+		}
+		if err != nil {
+			return nil, &OutputError{err}
+		}
+		if a, ok := w.(*AppendWriter); ok {
+			appended = append(appended, a)
+		}
+		writers = append(writers, w)
+	}
+	if o.xlsx != "" {
+		w, err := NewXLSXWriter(o.xlsx)
+		if err != nil {
+			return nil, &OutputError{err}
+		}
+		writers = append(writers, w)
+	}
+
+	if options.store != "" {
+		writers = append(writers, OpenStore(options.store))
+	}
+	// Объявления, которые уже есть во всех файлах, не загружаются повторно.
+	// Если есть другие получатели (--exec, --store), их нужно обработать
+	if len(appended) > 0 && len(appended) == len(writers) {
+		item_filter = item_filter.With(&knownRule{appended})
+	}
+
+	var w ItemWriter = writers
+	if dedupe {
+		w = NewDeduplicator(w, o.dedupe_threshold, o.dedupe_photos, o.one_per_cluster)
+	}
+	return w, nil
+}
+
 func main() {
-	var query string
-	var location string
-	var category string
-	var path_to_csvfile string
-	var verbose bool
-	var max_items int64
-	var pause int64
+	var search Search
+	var out OutputOptions
+	var filters FilterOptions
+	var locations []string
+	var locations_file string
 
 	var SelaAvitoCmd = &cobra.Command{
 		Use:     "selavito",
 		Short:   "Утилита для парсинга объявлений (вместе с телефонными номерами) с сайта avito.ru",
-		Example: "selavito -l moskva -q macbook --csv output.csv\niselavito -l sankt-peterburg -с rabota -q golang --csv output.csv",
+		Example: "selavito -l moskva -q macbook --csv output.csv\nselavito -l sankt-peterburg -с rabota -q golang --csv output.csv\nselavito -l moskva -q macbook -o - -f jsonl | jq .phone\nselavito -l moskva,moskovskaya_oblast,sankt-peterburg,kazan -q macbook -m 50 --csv output.csv",
 
 		Run: func(cmd *cobra.Command, args []string) {
-			InitLoggers(verbose)
+			InitLoggers(options.verbose)
 
-			if query == "" || path_to_csvfile == "" {
+			if search.Query == "" || out.Empty() {
 				cmd.Help()
 				return
 			}
 
-			throttleSet(pause)
-
-			var page_url string
-			counter := max_items
-			items := make(chan *Item)
-			save_wg := new(sync.WaitGroup)
-			parse_wg := new(sync.WaitGroup)
-
-			save_wg.Add(1)
-			go saveToCSV(path_to_csvfile, items, save_wg)
-
-			if category == "" {
-				page_url = fmt.Sprintf("%s/%s?q=%s", BASE_URL, location, query)
-			} else {
-				page_url = fmt.Sprintf("%s/%s/%s?q=%s", BASE_URL, location, category, query)
+			parser, err := NewParser(options.site_variant)
+			if err != nil {
+				fail(err)
+				return
 			}
 
-			items_done := 0
-
-			// max_items == 0 - без ограничения
-			for page_url != "" && (counter > 0 || max_items == 0) {
-				Info("Парсинг страницы: %s", page_url)
-
-				throttleWait()
-
-				doc, err := goquery.NewDocument(page_url)
-				if err != nil {
-					Error(err.Error())
-					break
+			if search.Since != "" {
+				if _, err := parseSince(search.Since, time.Now()); err != nil {
+					fail(err)
+					return
 				}
+			}
 
-				next_page_url, exists := doc.Find(".page-next").Find("a").First().Attr("href")
-				if exists {
-					next_page_url = fmt.Sprintf("%s%s", BASE_URL, next_page_url)
-					Info("Следующая страница: %s", next_page_url)
-				}
+			item_filter, err = filters.Filter()
+			if err != nil {
+				fail(err)
+				return
+			}
 
-				items_category := doc.Find(".nav-helper-header").First().Text()
-				if items_category == "" {
-					Error("Неверный формат страницы! Скорее всего ваш IP забанили!")
-					break
-				}
-				items_count := doc.Find(".nav-helper-text").First().Text()
-				items_category = strings.TrimSpace(items_category)
-				items_count = strings.TrimSpace(items_count)
-				if items_done == 0 {
-					fmt.Println("Категория:", items_category)
-					fmt.Println("Найдено объявлений:", items_count)
-				} else {
-					fmt.Printf("Процесс выполнения: %d/%s\n", items_done, items_count)
+			if locations_file != "" {
+				from_file, err := readLocationsFile(locations_file)
+				if err != nil {
+					fail(err)
+					return
 				}
-
-				doc.Find(".b-item").Each(func(i int, s *goquery.Selection) {
-					if counter > 0 {
-						item_url, exists := s.Find(".item-link").Attr("href")
-						if exists {
-							var item Item
-							item.header = s.Find(".header-text").First().Text()
-							item.location = s.Find(".info-location").First().Text()
-							item.url = fmt.Sprintf("%s%s", BASE_URL, item_url)
-							parse_wg.Add(1)
-							go parseItem(&item, parse_wg, items)
-							counter--
-							items_done++
-							Debug("%+v\n", item)
-						} else {
-							Error(".item-link not found")
-						}
-					}
-				})
-				page_url = next_page_url
+				locations = append(locations, from_file...)
+			}
+			if len(locations) == 0 {
+				locations = []string{"rossiya"}
+			}
+			if len(locations) > 1 {
+				multi_region = true
+				item_filter = item_filter.With(newDuplicateRule())
 			}
 
-			// Дожидаемся завершения работы всех парсеров...
-			parse_wg.Wait()
+			w, err := out.Open()
+			if err != nil {
+				fail(err)
+				return
+			}
 
-			// ...и только после закрываем канал
-			close(items)
+			finish, err := startSession()
+			if err != nil {
+				fail(err)
+				return
+			}
+			defer finish()
 
-			// Ждём пока данные окончательно сохранятся
-			save_wg.Wait()
+			_, err = runCrawl(w, func(items chan<- *Item) error {
+				return crawlRegions(parser, search, locations, items, out.Stop())
+			})
+			finishRun(err)
+			item_filter.Report()
 		},
 	}
 
-	SelaAvitoCmd.Flags().StringVarP(&query, "query", "q", "", "Строка для поиска")
-	SelaAvitoCmd.Flags().StringVarP(&location, "location", "l", "rossiya",
-		"Фильтр по региону (примеры: moskva, moskovskaya_oblast, sankt-peterburg)")
-	SelaAvitoCmd.Flags().StringVarP(&category, "category", "c", "",
+	SelaAvitoCmd.Flags().StringVarP(&search.Query, "query", "q", "", "Строка для поиска")
+	SelaAvitoCmd.Flags().StringSliceVarP(&locations, "location", "l", nil,
+		"Фильтр по региону, можно указать несколько через запятую (по умолчанию rossiya; примеры: moskva, moskovskaya_oblast, sankt-peterburg)")
+	SelaAvitoCmd.Flags().StringVar(&locations_file, "locations-file", "",
+		"Файл со списком регионов (по одному на строке)")
+	SelaAvitoCmd.Flags().StringVarP(&search.Category, "category", "c", "",
 		"Фильтр по категории (примеры: nedvizhimost, transport, rabota, rezume, vakansii)")
-	SelaAvitoCmd.Flags().StringVar(&path_to_csvfile, "csv", "",
-		"Путь к csv файлу для сохранения данных")
-
-	SelaAvitoCmd.Flags().BoolVarP(&verbose, "verbose", "v", false,
-		"Более подробный вывод в консоль")
-
-	SelaAvitoCmd.Flags().Int64VarP(&max_items, "max", "m", 1,
+	out.AddFlags(SelaAvitoCmd)
+	filters.AddFlags(SelaAvitoCmd)
+	SelaAvitoCmd.Flags().Int64VarP(&search.MaxItems, "max", "m", 1,
 		"Максимальное количество элементов для поиска (0 - без ограничения)")
-	SelaAvitoCmd.Flags().Int64VarP(&pause, "pause", "p", 0,
+	SelaAvitoCmd.Flags().StringVar(&search.Since, "since", "",
+		"Не обходить объявления старше границы: период (24h, 7d) или дата (2026-10-01); см. также --max-age")
+
+	SelaAvitoCmd.PersistentFlags().StringVar(&options.site_variant, "site-variant", "mobile",
+		"Версия сайта для парсинга: mobile (m.avito.ru) или desktop (www.avito.ru)")
+	SelaAvitoCmd.PersistentFlags().StringVar(&options.cookie_jar, "cookie-jar", filepath.Join(dataDir(), "cookies.json"),
+		"Файл для хранения cookies между запусками (пустая строка - не сохранять)")
+	SelaAvitoCmd.PersistentFlags().StringVar(&options.cookies_import, "cookies-import", "",
+		"Загрузить cookies, экспортированные из браузера (формат cookies.txt)")
+	SelaAvitoCmd.PersistentFlags().StringVar(&options.metrics_addr, "metrics-addr", "",
+		"Адрес для отдачи метрик в формате Prometheus (например :9090)")
+	SelaAvitoCmd.PersistentFlags().StringVar(&options.store, "store", filepath.Join(dataDir(), "items.jsonl"),
+		"Локальное хранилище всех найденных объявлений (пустая строка - не сохранять)")
+	SelaAvitoCmd.PersistentFlags().BoolVarP(&options.verbose, "verbose", "v", false,
+		"Более подробный вывод в консоль")
+	SelaAvitoCmd.PersistentFlags().Int64VarP(&options.pause, "pause", "p", 0,
 		"Пауза между запросами (в микросекундах)")
-
-	SelaAvitoCmd.Execute()
-
+	SelaAvitoCmd.PersistentFlags().BoolVar(&options.interactive, "interactive", isatty.IsTerminal(os.Stdin.Fd()),
+		"При капче приостанавливать обход и ждать, пока пользователь пройдёт проверку в браузере (по умолчанию - при запуске из терминала)")
+	SelaAvitoCmd.PersistentFlags().BoolVar(&options.fail_on_partial, "fail-on-partial", false,
+		"Завершаться с ошибкой (код 6), если часть объявлений не удалось обработать")
+
+	SelaAvitoCmd.AddCommand(ServeCmd)
+	SelaAvitoCmd.AddCommand(UICmd)
+	SelaAvitoCmd.AddCommand(ScheduleCmd)
+	SelaAvitoCmd.AddCommand(DaemonCmd)
+	SelaAvitoCmd.AddCommand(DiffCmd)
+	SelaAvitoCmd.AddCommand(HistoryCmd)
+	SelaAvitoCmd.AddCommand(SellerCmd)
+	SelaAvitoCmd.AddCommand(FillPhonesCmd)
+	SelaAvitoCmd.AddCommand(StatsCmd)
+
+	if err := SelaAvitoCmd.Execute(); err != nil && exit_code == EXIT_OK {
+		exit_code = EXIT_ERROR
+	}
+	exit()
 }
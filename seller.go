@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"sync"
+	"time"
+)
+
+// Телефоны продавца: у всех его объявлений один и тот же номер,
+// поэтому он запрашивается только для первого объявления.
+type sellerPhones struct {
+	mu     sync.Mutex
+	phones []string
+	done   bool
+}
+
+// Возвращает телефоны продавца, при первом вызове загружая их по phone_urls.
+// Если загрузить не удалось, следующий вызов попробует ещё раз.
+// Ошибка возвращается, только если обход нужно остановить (см. fatalError).
+func (s *sellerPhones) get(phone_urls []string, referer string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return s.phones, nil
+	}
+	for _, phone_url := range phone_urls {
+		phone, err := getPhone(phone_url, referer)
+		if err != nil {
+			itemFailed(err)
+			if fatalError(err) {
+				return s.phones, err
+			}
+			Error("%s", err.Error())
+			continue
+		}
+		s.phones = append(s.phones, phone)
+	}
+	s.done = len(s.phones) > 0
+	return s.phones, nil
+}
+
+// Находит ссылку на профиль продавца. Если передана ссылка на объявление,
+// профиль ищется на странице объявления.
+func sellerProfileURL(parser Parser, url string) (string, error) {
+	if isSellerURL(url) {
+		return url, nil
+	}
+
+	throttleWait()
+	doc, err := fetchDocument("item", url, "")
+	if err != nil {
+		return "", err
+	}
+	profile_url := parser.SellerURL(doc)
+	if profile_url == "" {
+		return "", fmt.Errorf("Не удалось найти профиль продавца на странице %s", url)
+	}
+	return profile_url, nil
+}
+
+// Обходит все активные объявления продавца и отправляет их в items
+func crawlSeller(parser Parser, profile_url string, max_items int64, items chan<- *Item, stop <-chan struct{}) error {
+	phones := &sellerPhones{}
+	return crawlPages(profile_url, parser.ParseSellerPage, func(item *Item) error {
+		phone_urls, err := fetchItem(parser, item)
+		if err != nil {
+			itemFailed(err)
+			if fatalError(err) {
+				return err
+			}
+			Error("%s", err.Error())
+			return nil
+		}
+		if !item_filter.Match(item) {
+			return nil
+		}
+		downloadPhotos(item)
+		if no_phones {
+			items <- item
+			return nil
+		}
+		found, err := phones.get(phone_urls, item.url)
+		emitPhones(item, found, items)
+		return err
+	}, max_items, time.Time{}, stop, nil)
+}
+
+var (
+	seller_out     OutputOptions
+	seller_filters FilterOptions
+	seller_max     int64
+)
+
+var SellerCmd = &cobra.Command{
+	Use:   "seller <ссылка на профиль|ссылка на объявление>",
+	Short: "Собрать все активные объявления продавца",
+	Long: `Обходит все активные объявления продавца и сохраняет их так же, как обычный поиск.
+Можно передать ссылку на профиль продавца или на любое его объявление.
+Телефон продавца запрашивается только один раз.`,
+	Example: "selavito seller https://m.avito.ru/moskva/mebel/kreslo_123456789 --csv seller.csv\nselavito seller https://www.avito.ru/user/0a1b2c3d/profile --site-variant desktop -o - -f jsonl",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if len(args) != 1 || seller_out.Empty() {
+			cmd.Help()
+			return
+		}
+
+		parser, err := NewParser(options.site_variant)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		item_filter, err = seller_filters.Filter()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		w, err := seller_out.Open()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		finish, err := startSession()
+		if err != nil {
+			fail(err)
+			w.Close()
+			return
+		}
+		defer finish()
+
+		profile_url, err := sellerProfileURL(parser, args[0])
+		if err != nil {
+			fail(err)
+			w.Close()
+			return
+		}
+		Info("Профиль продавца: %s", profile_url)
+
+		count, err := runCrawl(w, func(items chan<- *Item) error {
+			return crawlSeller(parser, profile_url, seller_max, items, seller_out.Stop())
+		})
+		Info("Сохранено объявлений: %d", count)
+		finishRun(err)
+		item_filter.Report()
+	},
+}
+
+func init() {
+	seller_out.AddFlags(SellerCmd)
+	seller_filters.AddFlags(SellerCmd)
+	SellerCmd.Flags().Int64VarP(&seller_max, "max", "m", 0,
+		"Максимальное количество объявлений (0 - все объявления продавца)")
+}
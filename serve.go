@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	JOB_QUEUED    = "queued"
+	JOB_RUNNING   = "running"
+	JOB_DONE      = "done"
+	JOB_FAILED    = "failed"
+	JOB_CANCELLED = "cancelled"
+)
+
+// Задание на поиск, запущенное через HTTP API
+type Job struct {
+	mu sync.Mutex
+
+	id          string
+	search      Search
+	site        string
+	parser      Parser
+	store       *Store
+	status      string
+	err         string
+	done        int
+	total       string
+	items       []*Item
+	created     time.Time
+	finished    time.Time
+	stop        chan struct{}
+	stop_once   sync.Once
+	items_added chan struct{}
+}
+
+type jobJSON struct {
+	ID          string     `json:"id"`
+	Search      Search     `json:"search"`
+	SiteVariant string     `json:"site_variant"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Done        int        `json:"done"`
+	Total       string     `json:"total,omitempty"`
+	Items       int        `json:"items"`
+	Created     time.Time  `json:"created"`
+	Finished    *time.Time `json:"finished,omitempty"`
+}
+
+func (job *Job) MarshalJSON() ([]byte, error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	j := jobJSON{
+		ID:          job.id,
+		Search:      job.search,
+		SiteVariant: job.site,
+		Status:      job.status,
+		Error:       job.err,
+		Done:        job.done,
+		Total:       job.total,
+		Items:       len(job.items),
+		Created:     job.created,
+	}
+	if !job.finished.IsZero() {
+		j.Finished = &job.finished
+	}
+	return json.Marshal(j)
+}
+
+func (job *Job) run() {
+	job.mu.Lock()
+	if job.status != JOB_QUEUED {
+		job.mu.Unlock()
+		return
+	}
+	job.status = JOB_RUNNING
+	job.mu.Unlock()
+
+	items := make(chan *Item)
+	collected := make(chan bool)
+	go func() {
+		for item := range items {
+			job.mu.Lock()
+			job.items = append(job.items, item)
+			close(job.items_added)
+			job.items_added = make(chan struct{})
+			job.mu.Unlock()
+			if job.store != nil {
+				if err := job.store.Write(item); err != nil {
+					Error("Не удалось сохранить объявление: %s", err)
+				}
+			}
+			metrics.ItemEmitted()
+		}
+		collected <- true
+	}()
+
+	err := crawl(job.parser, job.search, items, job.stop, func(done int, total string) {
+		job.mu.Lock()
+		job.done = done
+		job.total = total
+		job.mu.Unlock()
+	})
+	close(items)
+	<-collected
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	switch {
+	case stopped(job.stop):
+		job.status = JOB_CANCELLED
+	case err != nil:
+		job.status = JOB_FAILED
+		job.err = err.Error()
+	default:
+		job.status = JOB_DONE
+	}
+	job.finished = time.Now()
+	close(job.items_added)
+	job.items_added = nil
+	Info("Задание %s завершено: %s", job.id, job.status)
+}
+
+func (job *Job) cancel() {
+	job.stop_once.Do(func() { close(job.stop) })
+}
+
+// Завершено ли задание (результаты больше не меняются)
+func (job *Job) isFinished() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return !job.finished.IsZero()
+}
+
+// Возвращает объявления начиная с offset и канал, который закроется
+// при появлении новых (nil - задание завершено и новых не будет)
+func (job *Job) itemsFrom(offset, limit int) ([]*Item, chan struct{}) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if offset > len(job.items) {
+		offset = len(job.items)
+	}
+	end := len(job.items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return job.items[offset:end], job.items_added
+}
+
+// Сервер HTTP API. Все задания используют общую паузу между запросами
+// и общий HTTP клиент (с общими cookies и прокси из HTTP_PROXY/HTTPS_PROXY).
+// Результаты заданий хранятся в памяти, поэтому сервер помнит не больше
+// keep_jobs завершённых заданий: при создании нового самые старые забываются.
+type Server struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	last_id int
+	running sync.WaitGroup
+
+	// Хранилище, в которое попадают результаты всех заданий (может быть nil)
+	store *Store
+
+	// Сколько завершённых заданий хранить (0 - без ограничения)
+	keep_jobs int
+}
+
+func NewServer(store *Store, keep_jobs int) *Server {
+	return &Server{jobs: make(map[string]*Job), store: store, keep_jobs: keep_jobs}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "jobs" && r.Method == "GET":
+		s.listJobs(w, r)
+	case path == "jobs" && r.Method == "POST":
+		s.createJob(w, r)
+	case len(parts) == 2 && parts[0] == "jobs":
+		job := s.job(parts[1])
+		if job == nil {
+			writeError(w, http.StatusNotFound, "Задание не найдено")
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, job)
+		case "DELETE":
+			// Запущенное задание отменяется, завершённое удаляется вместе с результатами
+			if job.isFinished() {
+				s.mu.Lock()
+				delete(s.jobs, job.id)
+				s.mu.Unlock()
+			} else {
+				job.cancel()
+			}
+			writeJSON(w, http.StatusOK, job)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
+		}
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "items" && r.Method == "GET":
+		job := s.job(parts[1])
+		if job == nil {
+			writeError(w, http.StatusNotFound, "Задание не найдено")
+			return
+		}
+		if r.URL.Query().Get("stream") != "" {
+			s.streamItems(w, r, job)
+		} else {
+			s.pageItems(w, r, job)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Неизвестный адрес")
+	}
+}
+
+func (s *Server) job(id string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// Задания в порядке создания. Вызывается под s.mu
+func (s *Server) sortedJobs() []*Job {
+	ids := make([]int, 0, len(s.jobs))
+	for id := range s.jobs {
+		n, _ := strconv.Atoi(id)
+		ids = append(ids, n)
+	}
+	sort.Ints(ids)
+	jobs := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, s.jobs[strconv.Itoa(id)])
+	}
+	return jobs
+}
+
+// Забывает самые старые завершённые задания сверх keep_jobs. Вызывается под s.mu
+func (s *Server) forgetOldJobs() {
+	if s.keep_jobs <= 0 {
+		return
+	}
+	var finished []*Job
+	for _, job := range s.sortedJobs() {
+		if job.isFinished() {
+			finished = append(finished, job)
+		}
+	}
+	for len(finished) > s.keep_jobs {
+		Debug("Задание %s удалено из памяти", finished[0].id)
+		delete(s.jobs, finished[0].id)
+		finished = finished[1:]
+	}
+}
+
+// GET /jobs
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := s.sortedJobs()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// POST /jobs {"query": "...", "location": "...", "category": "...", "max_items": 10, "since": "24h", "site_variant": "mobile"}
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Search
+		SiteVariant string `json:"site_variant"`
+	}
+	req.Location = "rossiya"
+	req.MaxItems = 1
+	req.SiteVariant = options.site_variant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Неверный запрос: %s", err))
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "Не указана строка для поиска (query)")
+		return
+	}
+	if req.Since != "" {
+		if _, err := parseSince(req.Since, time.Now()); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	parser, err := NewParser(req.SiteVariant)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.last_id++
+	job := &Job{
+		id:          strconv.Itoa(s.last_id),
+		search:      req.Search,
+		site:        req.SiteVariant,
+		parser:      parser,
+		store:       s.store,
+		status:      JOB_QUEUED,
+		created:     time.Now(),
+		stop:        make(chan struct{}),
+		items_added: make(chan struct{}),
+	}
+	s.jobs[job.id] = job
+	s.forgetOldJobs()
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	Info("Новое задание %s: %+v", job.id, job.search)
+	go func() {
+		defer s.running.Done()
+		job.run()
+	}()
+
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// Отменяет все задания и дожидается их завершения
+func (s *Server) Stop() {
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		job.cancel()
+	}
+	s.mu.Unlock()
+	s.running.Wait()
+}
+
+// GET /jobs/{id}/items?offset=0&limit=100
+func (s *Server) pageItems(w http.ResponseWriter, r *http.Request, job *Job) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	items, _ := job.itemsFrom(offset, limit)
+	if items == nil {
+		items = []*Item{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"offset": offset,
+		"items":  items,
+	})
+}
+
+// GET /jobs/{id}/items?stream=1 - объявления в формате JSON Lines
+// по мере их появления, пока задание не завершится
+func (s *Server) streamItems(w http.ResponseWriter, r *http.Request, job *Job) {
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	closed := closeNotify(w)
+	enc := json.NewEncoder(w)
+
+	offset := 0
+	for {
+		items, added := job.itemsFrom(offset, 0)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return
+			}
+		}
+		offset += len(items)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if added == nil {
+			return
+		}
+		select {
+		case <-added:
+		case <-closed:
+			return
+		}
+	}
+}
+
+func closeNotify(w http.ResponseWriter) <-chan bool {
+	if cn, ok := w.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Error("%s", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+var (
+	serve_addr string
+	keep_jobs  int
+)
+
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Запустить HTTP API для запуска поисков и получения результатов",
+	Long: `Запускает HTTP сервер для управления заданиями на поиск:
+
+  POST   /jobs                       создать задание {"query", "location", "category", "max_items", "site_variant"}
+  GET    /jobs                       список заданий
+  GET    /jobs/{id}                  состояние задания
+  GET    /jobs/{id}/items            результаты постранично (?offset=0&limit=100)
+  GET    /jobs/{id}/items?stream=1   результаты в формате JSON Lines по мере появления
+  DELETE /jobs/{id}                  отменить задание, а завершённое - удалить
+
+Все задания используют общую паузу между запросами (--pause) и общие cookies.
+Результаты хранятся в памяти: сервер помнит не больше --keep-jobs завершённых заданий.`,
+	Example: "selavito serve --addr :8080 -p 2000",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		finish, err := startSession()
+		if err != nil {
+			fail(err)
+			return
+		}
+		defer finish()
+
+		var store *Store
+		if options.store != "" {
+			store = OpenStore(options.store)
+			defer store.Close()
+		}
+
+		listener, err := net.Listen("tcp", serve_addr)
+		if err != nil {
+			fail(err)
+			return
+		}
+		server := NewServer(store, keep_jobs)
+		mux := http.NewServeMux()
+		mux.Handle("/jobs", server)
+		mux.Handle("/jobs/", server)
+		mux.Handle("/metrics", metrics)
+		Info("HTTP API доступно по адресу: http://%s/jobs", serve_addr)
+
+		served := make(chan error, 1)
+		go func() {
+			served <- http.Serve(listener, mux)
+		}()
+
+		// Как и daemon, по сигналу завершаем задания, чтобы сохранить cookies и хранилище
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		select {
+		case err := <-served:
+			fail(err)
+		case <-interrupt:
+			Info("Завершение работы, отменяем запущенные задания...")
+			listener.Close()
+		}
+		server.Stop()
+	},
+}
+
+func init() {
+	ServeCmd.Flags().StringVar(&serve_addr, "addr", "127.0.0.1:8080", "Адрес HTTP сервера")
+	ServeCmd.Flags().IntVar(&keep_jobs, "keep-jobs", 100,
+		"Сколько завершённых заданий хранить в памяти (0 - без ограничения)")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Завершённое задание с одним объявлением
+func finishedJob(id int) *Job {
+	return &Job{
+		id:       strconv.Itoa(id),
+		status:   JOB_DONE,
+		items:    []*Item{{id: strconv.Itoa(id)}},
+		created:  time.Now(),
+		finished: time.Now(),
+		stop:     make(chan struct{}),
+	}
+}
+
+func TestServerDeleteFinishedJob(t *testing.T) {
+	s := NewServer(nil, 0)
+	s.jobs["1"] = finishedJob(1)
+	running := &Job{id: "2", status: JOB_RUNNING, stop: make(chan struct{})}
+	s.jobs["2"] = running
+
+	for _, id := range []string{"1", "2"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("DELETE", "/jobs/"+id, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("DELETE /jobs/%s: %d", id, w.Code)
+		}
+	}
+	if s.job("1") != nil {
+		t.Error("завершённое задание не удалено")
+	}
+	if s.job("2") == nil || !stopped(running.stop) {
+		t.Error("запущенное задание должно отменяться, а не удаляться")
+	}
+}
+
+func TestServerForgetOldJobs(t *testing.T) {
+	s := NewServer(nil, 2)
+	for id := 1; id <= 4; id++ {
+		s.jobs[strconv.Itoa(id)] = finishedJob(id)
+	}
+	s.jobs["5"] = &Job{id: "5", status: JOB_RUNNING, stop: make(chan struct{})}
+
+	s.mu.Lock()
+	s.forgetOldJobs()
+	s.mu.Unlock()
+
+	for id, kept := range map[string]bool{"1": false, "2": false, "3": true, "4": true, "5": true} {
+		if (s.job(id) != nil) != kept {
+			t.Errorf("задание %s: сохранено %v, ожидалось %v", id, !kept, kept)
+		}
+	}
+}
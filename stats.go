@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Распределение цен
+type PriceStats struct {
+	Count  int   `json:"count"`
+	Min    int64 `json:"min"`
+	Max    int64 `json:"max"`
+	Mean   int64 `json:"mean"`
+	Median int64 `json:"median"`
+	P10    int64 `json:"p10"`
+	P25    int64 `json:"p25"`
+	P75    int64 `json:"p75"`
+	P90    int64 `json:"p90"`
+}
+
+// Количество объявлений для значения (региона, дня, телефона, слова)
+type StatsCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Продавцы: частные лица и компании
+type SellerStats struct {
+	Private      int     `json:"private"`
+	Company      int     `json:"company"`
+	Unknown      int     `json:"unknown"`
+	PrivateShare float64 `json:"private_share"`
+	CompanyShare float64 `json:"company_share"`
+}
+
+// Сводка по собранным объявлениям
+type Stats struct {
+	Items     int          `json:"items"`
+	Prices    *PriceStats  `json:"prices,omitempty"`
+	Locations []StatsCount `json:"locations"`
+	Days      []StatsCount `json:"days"`
+	Sellers   SellerStats  `json:"sellers"`
+	Phones    []StatsCount `json:"phones"`
+	Words     []StatsCount `json:"words"`
+}
+
+// Слова, которые не учитываются в частых словах заголовков
+var stats_stop_words = map[string]bool{
+	"для": true, "под": true, "без": true, "или": true, "при": true, "над": true,
+	"the": true, "and": true, "for": true,
+}
+
+// Собирает сводку по объявлениям (по одной записи на объявление),
+// top - сколько самых частых телефонов и слов показывать
+func collectStats(all []*Item, top int) *Stats {
+	items := latestItemsInOrder(all)
+	stats := &Stats{Items: len(items)}
+
+	var prices []int64
+	locations := make(map[string]int)
+	days := make(map[string]int)
+	phones := make(map[string]int)
+	words := make(map[string]int)
+	for _, item := range items {
+		if price, ok := parsePrice(item.price); ok {
+			prices = append(prices, price)
+		}
+		if item.location != "" {
+			locations[item.location]++
+		}
+
+		day := item.published
+		if day.IsZero() {
+			day = item.seen
+		}
+		if !day.IsZero() {
+			days[day.Local().Format("2006-01-02")]++
+		}
+
+		switch item.seller_type {
+		case "private":
+			stats.Sellers.Private++
+		case "company":
+			stats.Sellers.Company++
+		default:
+			stats.Sellers.Unknown++
+		}
+
+		// Каждое слово считается один раз на объявление
+		for word := range titleWords(item.header) {
+			words[word]++
+		}
+	}
+
+	// У объявления с несколькими телефонами на каждый номер своя запись,
+	// поэтому телефоны считаются по всем записям, но один раз на объявление
+	seen_phones := make(map[string]bool)
+	for _, item := range all {
+		phone := normalizePhone(item.phone)
+		if phone == "" || seen_phones[item.key()+"\n"+phone] {
+			continue
+		}
+		seen_phones[item.key()+"\n"+phone] = true
+		phones[phone]++
+	}
+
+	stats.Prices = priceStats(prices)
+	stats.Locations = topCounts(locations, 0)
+	stats.Days = topCounts(days, 0)
+	sort.Sort(byValue(stats.Days))
+	stats.Phones = topCounts(phones, top)
+	stats.Words = topCounts(words, top)
+	if stats.Items > 0 {
+		stats.Sellers.PrivateShare = float64(stats.Sellers.Private) / float64(stats.Items)
+		stats.Sellers.CompanyShare = float64(stats.Sellers.Company) / float64(stats.Items)
+	}
+	return stats
+}
+
+func titleWords(header string) map[string]bool {
+	result := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(header), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) < 3 || stats_stop_words[word] || strings.Trim(word, "0123456789") == "" {
+			continue
+		}
+		result[word] = true
+	}
+	return result
+}
+
+func priceStats(prices []int64) *PriceStats {
+	if len(prices) == 0 {
+		return nil
+	}
+	sort.Sort(byPrice(prices))
+	var sum int64
+	for _, price := range prices {
+		sum += price
+	}
+	return &PriceStats{
+		Count:  len(prices),
+		Min:    prices[0],
+		Max:    prices[len(prices)-1],
+		Mean:   sum / int64(len(prices)),
+		Median: percentile(prices, 50),
+		P10:    percentile(prices, 10),
+		P25:    percentile(prices, 25),
+		P75:    percentile(prices, 75),
+		P90:    percentile(prices, 90),
+	}
+}
+
+// Перцентиль отсортированных цен с линейной интерполяцией
+func percentile(sorted []int64, p float64) int64 {
+	pos := p / 100 * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return int64(math.Floor(float64(sorted[i]) + frac*float64(sorted[i+1]-sorted[i]) + 0.5))
+}
+
+// Значения по убыванию количества (при равенстве - по алфавиту),
+// top - ограничение количества (0 - без ограничения)
+func topCounts(counts map[string]int, top int) []StatsCount {
+	result := []StatsCount{}
+	for value, count := range counts {
+		result = append(result, StatsCount{value, count})
+	}
+	sort.Sort(byCount(result))
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
+
+type byPrice []int64
+
+func (s byPrice) Len() int           { return len(s) }
+func (s byPrice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byPrice) Less(i, j int) bool { return s[i] < s[j] }
+
+type byCount []StatsCount
+
+func (s byCount) Len() int      { return len(s) }
+func (s byCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	return s[i].Value < s[j].Value
+}
+
+type byValue []StatsCount
+
+func (s byValue) Len() int           { return len(s) }
+func (s byValue) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byValue) Less(i, j int) bool { return s[i].Value < s[j].Value }
+
+func (stats *Stats) Print(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Объявлений:\t%d\n", stats.Items)
+
+	if p := stats.Prices; p != nil {
+		fmt.Fprintf(w, "\nЦены (объявлений с ценой: %d)\n", p.Count)
+		for _, row := range []struct {
+			name  string
+			value int64
+		}{
+			{"минимум", p.Min}, {"10%", p.P10}, {"25%", p.P25}, {"медиана", p.Median},
+			{"75%", p.P75}, {"90%", p.P90}, {"максимум", p.Max}, {"среднее", p.Mean},
+		} {
+			fmt.Fprintf(w, "  %s\t%d\n", row.name, row.value)
+		}
+	}
+
+	s := stats.Sellers
+	fmt.Fprintf(w, "\nПродавцы\n")
+	fmt.Fprintf(w, "  частные\t%d\t%.1f%%\n", s.Private, s.PrivateShare*100)
+	fmt.Fprintf(w, "  компании\t%d\t%.1f%%\n", s.Company, s.CompanyShare*100)
+	if s.Unknown > 0 {
+		fmt.Fprintf(w, "  неизвестно\t%d\n", s.Unknown)
+	}
+
+	for _, section := range []struct {
+		title  string
+		counts []StatsCount
+	}{
+		{"По местоположению", stats.Locations},
+		{"По дням", stats.Days},
+		{"Частые телефоны", stats.Phones},
+		{"Частые слова в заголовках", stats.Words},
+	} {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", section.title)
+		for _, c := range section.counts {
+			fmt.Fprintf(w, "  %s\t%d\n", c.Value, c.Count)
+		}
+	}
+}
+
+var (
+	stats_json bool
+	stats_top  int
+)
+
+var StatsCmd = &cobra.Command{
+	Use:   "stats [<файл с результатами>]",
+	Short: "Сводка по собранным объявлениям",
+	Long: `Показывает распределение цен (минимум, медиана, перцентили), количество объявлений по местоположению и по дням,
+долю частных продавцов и компаний, самые частые телефоны и слова в заголовках.
+Читает файл с результатами (jsonl или csv) или, если файл не указан, локальное хранилище (см. --store).`,
+	Example: "selavito stats kreslo.jsonl\nselavito stats --top 20\nselavito stats kreslo.csv --json | jq .prices",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		var all []*Item
+		var err error
+		switch len(args) {
+		case 0:
+			if options.store == "" {
+				fail(errors.New("Не указано локальное хранилище (--store)"))
+				return
+			}
+			all, err = OpenStore(options.store).ReadAll()
+		case 1:
+			all, err = readItemsFile(args[0])
+		default:
+			cmd.Help()
+			return
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		stats := collectStats(all, stats_top)
+		if stats_json {
+			if err := json.NewEncoder(os.Stdout).Encode(stats); err != nil {
+				fail(err)
+			}
+			return
+		}
+		stats.Print(os.Stdout)
+	},
+}
+
+func init() {
+	StatsCmd.Flags().BoolVar(&stats_json, "json", false, "Вывести результат в формате JSON")
+	StatsCmd.Flags().IntVar(&stats_top, "top", 10, "Сколько самых частых телефонов и слов показывать (0 - все)")
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Телефоны считаются по всем записям, но один раз на объявление
+func TestCollectStatsPhones(t *testing.T) {
+	all := []*Item{
+		{id: "1", price: "1 000 руб.", phone: "8 900 111-22-33"},
+		{id: "1", price: "1 000 руб.", phone: "8 900 444-55-66"},
+		{id: "2", price: "3 000 руб.", phone: "+7 900 111-22-33"},
+		{id: "2", price: "3 000 руб.", phone: "89001112233"},
+		{id: "3", price: "5 000 руб."},
+	}
+	stats := collectStats(all, 10)
+	if stats.Items != 3 {
+		t.Errorf("объявлений: %d, ожидалось 3", stats.Items)
+	}
+	if stats.Prices == nil || stats.Prices.Count != 3 {
+		t.Errorf("цены: %+v, ожидалось 3", stats.Prices)
+	}
+	want := []StatsCount{{"9001112233", 2}, {"9004445566", 1}}
+	if !reflect.DeepEqual(stats.Phones, want) {
+		t.Errorf("телефоны: %+v, ожидалось %+v", stats.Phones, want)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store - локальное хранилище объявлений в формате JSON Lines.
+// Каждый запуск дописывает в файл все найденные объявления, поэтому
+// одно и то же объявление может встречаться несколько раз - последняя
+// запись считается актуальной.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func OpenStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Дописывает объявление в хранилище
+func (s *Store) Write(item *Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+		s.enc = json.NewEncoder(f)
+	}
+	return s.enc.Encode(item)
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Читает все записи хранилища в порядке добавления
+func (s *Store) ReadAll() ([]*Item, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readJSONLines(f)
+}
+
+// Актуальные версии объявлений (последняя запись для каждого ID),
+// самые свежие - первыми
+func (s *Store) Items() ([]*Item, error) {
+	all, err := s.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return latestItems(all), nil
+}
+
+func latestItems(all []*Item) []*Item {
+	items := latestItemsInOrder(all)
+	sort.Stable(sort.Reverse(bySeen(items)))
+	return items
+}
+
+func readJSONLines(r io.Reader) ([]*Item, error) {
+	var items []*Item
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && len(bytes.TrimSpace(line)) > 0 {
+			item := &Item{}
+			if jerr := json.Unmarshal(line, item); jerr != nil {
+				return nil, jerr
+			}
+			items = append(items, item)
+		}
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+type bySeen []*Item
+
+func (s bySeen) Len() int           { return len(s) }
+func (s bySeen) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySeen) Less(i, j int) bool { return s[i].seen.Before(s[j].seen) }
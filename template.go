@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Функции, доступные в шаблонах вывода
+var template_funcs = template.FuncMap{
+	// Экранирование значения для csv: {{csv .Header}}
+	"csv": csvField,
+	// Значение в формате JSON (строка в кавычках, массив и т.п.): {{json .Params}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// Экранирование для HTML: {{html .Description}}
+	"html": template.HTMLEscapeString,
+	// Объединение списка: {{join .Photos ";"}}
+	"join": func(list []string, sep string) string {
+		return strings.Join(list, sep)
+	},
+	// Форматирование даты: {{date "02.01.2006" .Published}}
+	"date": func(layout string, t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(layout)
+	},
+}
+
+// Значение для csv с любым разделителем (запятая, точка с запятой, табуляция):
+// в кавычки берётся значение, в котором есть разделитель, кавычка или перевод строки
+func csvField(s string) string {
+	if s == "" || (!strings.ContainsAny(s, ",;\t\"\r\n") && s[0] != ' ') {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// Разбирает шаблон объявления из файла или строки (inline).
+// Шаблоны "header" и "footer" выводятся в начале и в конце файла,
+// их можно задать в файле через {{define "header"}}...{{end}} или строками header и footer.
+// К строковому шаблону объявления добавляется перевод строки.
+func parseItemTemplate(path, inline, header, footer string) (*template.Template, error) {
+	text := inline
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	} else if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	tmpl, err := template.New("item").Funcs(template_funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Неверный шаблон: %s", err)
+	}
+	for name, text := range map[string]string{"header": header, "footer": footer} {
+		if text == "" {
+			continue
+		}
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("Неверный шаблон %s: %s", name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// Записывает объявления по шаблону text/template.
+// В шаблоне объявления доступны поля .ID, .Header, .Price, .Phone, .URL и т.д.
+// (как в JSON), в шаблоне footer - количество объявлений .Count.
+type TemplateWriter struct {
+	file  io.WriteCloser
+	tmpl  *template.Template
+	count int
+}
+
+func NewTemplateWriter(path string, tmpl *template.Template) (*TemplateWriter, error) {
+	f, err := openOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &TemplateWriter{file: f, tmpl: tmpl}
+	if err := t.execute("header", nil); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *TemplateWriter) execute(name string, data interface{}) error {
+	if t.tmpl.Lookup(name) == nil {
+		return nil
+	}
+	return t.tmpl.ExecuteTemplate(t.file, name, data)
+}
+
+func (t *TemplateWriter) Write(item *Item) error {
+	t.count++
+	return t.execute("item", item.view())
+}
+
+func (t *TemplateWriter) Close() error {
+	err := t.execute("footer", struct{ Count int }{t.count})
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVField(t *testing.T) {
+	for _, c := range []struct {
+		s, want string
+	}{
+		{"", ""},
+		{"Кресло", "Кресло"},
+		{"Кресло; б/у", `"Кресло; б/у"`},
+		{"Кресло, б/у", `"Кресло, б/у"`},
+		{"Кресло\tб/у", "\"Кресло\tб/у\""},
+		{`Кресло "Бюрократ"`, `"Кресло ""Бюрократ"""`},
+		{"Кресло\nб/у", "\"Кресло\nб/у\""},
+		{" Кресло", `" Кресло"`},
+	} {
+		if got := csvField(c.s); got != c.want {
+			t.Errorf("csvField(%q) = %q, ожидалось %q", c.s, got, c.want)
+		}
+	}
+}
+
+// Строка по шаблону из README читается обратно как две колонки
+func TestTemplateCSVSemicolon(t *testing.T) {
+	tmpl, err := parseItemTemplate("", "{{.Phone}};{{csv .Header}}", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	item := &Item{phone: "+79990001122", header: "Кресло; б/у"}
+	if err := tmpl.ExecuteTemplate(&b, "item", item.view()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := csv.NewReader(strings.NewReader(b.String()))
+	r.Comma = ';'
+	record, err := r.Read()
+	if err != nil {
+		t.Fatalf("%q: %s", b.String(), err)
+	}
+	if want := []string{"+79990001122", "Кресло; б/у"}; !reflect.DeepEqual(record, want) {
+		t.Errorf("%q прочитано как %q, ожидалось %q", b.String(), record, want)
+	}
+}
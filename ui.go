@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"github.com/kulapard/selavito/Godeps/_workspace/src/github.com/spf13/cobra"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Условия отбора объявлений в веб-интерфейсе
+type ItemQuery struct {
+	text      string
+	location  string
+	price_min int64
+	price_max int64
+	date_from time.Time
+	date_to   time.Time
+}
+
+func parseItemQuery(r *http.Request) ItemQuery {
+	v := r.URL.Query()
+	q := ItemQuery{
+		text:     strings.ToLower(strings.TrimSpace(v.Get("q"))),
+		location: strings.ToLower(strings.TrimSpace(v.Get("location"))),
+	}
+	q.price_min, _ = strconv.ParseInt(v.Get("price_min"), 10, 64)
+	q.price_max, _ = strconv.ParseInt(v.Get("price_max"), 10, 64)
+	if t, err := time.ParseInLocation("2006-01-02", v.Get("date_from"), time.Local); err == nil {
+		q.date_from = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v.Get("date_to"), time.Local); err == nil {
+		q.date_to = t.AddDate(0, 0, 1)
+	}
+	return q
+}
+
+func (q ItemQuery) Match(item *Item) bool {
+	if q.text != "" {
+		text := strings.ToLower(item.header + "\n" + item.description + "\n" + strings.Join(item.params, "\n"))
+		if !strings.Contains(text, q.text) {
+			return false
+		}
+	}
+	if q.location != "" && !strings.Contains(strings.ToLower(item.location), q.location) {
+		return false
+	}
+	if q.price_min > 0 || q.price_max > 0 {
+		price, ok := parsePrice(item.price)
+		if !ok || (q.price_min > 0 && price < q.price_min) || (q.price_max > 0 && price > q.price_max) {
+			return false
+		}
+	}
+	if !q.date_from.IsZero() || !q.date_to.IsZero() {
+		date := item.published
+		if date.IsZero() {
+			date = item.seen
+		}
+		if (!q.date_from.IsZero() && date.Before(q.date_from)) || (!q.date_to.IsZero() && !date.Before(q.date_to)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Веб-интерфейс для просмотра объявлений из локального хранилища
+type UI struct {
+	store *Store
+}
+
+func (ui *UI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(ui_html))
+	case "/api/items":
+		items, ok := ui.items(w, r)
+		if ok {
+			writeJSON(w, http.StatusOK, items)
+		}
+	case "/export.csv":
+		items, ok := ui.items(w, r)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="selavito.csv"`)
+		csv_writer, err := newCSVWriter(nopCloser{w}, true)
+		if err != nil {
+			Error("%s", err.Error())
+			return
+		}
+		for _, item := range items {
+			if err := csv_writer.Write(item); err != nil {
+				Error("%s", err.Error())
+				return
+			}
+		}
+		csv_writer.Close()
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (ui *UI) items(w http.ResponseWriter, r *http.Request) ([]*Item, bool) {
+	all, err := ui.store.Items()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	q := parseItemQuery(r)
+	items := []*Item{}
+	for _, item := range all {
+		if q.Match(item) {
+			items = append(items, item)
+		}
+	}
+	return items, true
+}
+
+var ui_addr string
+
+var UICmd = &cobra.Command{
+	Use:     "ui",
+	Short:   "Запустить веб-интерфейс для просмотра найденных объявлений",
+	Long:    "Запускает веб-интерфейс для поиска и фильтрации объявлений из локального хранилища (см. --store).",
+	Example: "selavito ui --addr 127.0.0.1:8081",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		InitLoggers(options.verbose)
+
+		if options.store == "" {
+			fail(errors.New("Не указано локальное хранилище (--store)"))
+			return
+		}
+
+		Info("Веб-интерфейс доступен по адресу: http://%s/", ui_addr)
+		if err := http.ListenAndServe(ui_addr, &UI{store: OpenStore(options.store)}); err != nil {
+			fail(err)
+		}
+	},
+}
+
+func init() {
+	UICmd.Flags().StringVar(&ui_addr, "addr", "127.0.0.1:8081", "Адрес веб-интерфейса")
+}
+
+const ui_html = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>SelAvito</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+form { margin-bottom: 15px; }
+form input { margin-right: 10px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 6px; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+td.price { white-space: nowrap; text-align: right; }
+#count { color: #666; margin: 10px 0; }
+</style>
+</head>
+<body>
+<h1>SelAvito</h1>
+<form id="filter">
+<input name="q" placeholder="Текст">
+<input name="location" placeholder="Местоположение">
+Цена: <input name="price_min" type="number" placeholder="от" size="8">
+<input name="price_max" type="number" placeholder="до" size="8">
+Дата: <input name="date_from" type="date">
+<input name="date_to" type="date">
+<button type="submit">Найти</button>
+<a id="export" href="/export.csv">Экспорт в CSV</a>
+</form>
+<div id="count"></div>
+<table>
+<thead><tr><th>Заголовок</th><th>Цена</th><th>Телефон</th><th>Местоположение</th><th>Дата</th></tr></thead>
+<tbody id="items"></tbody>
+</table>
+<script>
+var form = document.getElementById("filter");
+
+function query() {
+	var parts = [];
+	for (var i = 0; i < form.elements.length; i++) {
+		var el = form.elements[i];
+		if (el.name && el.value) {
+			parts.push(encodeURIComponent(el.name) + "=" + encodeURIComponent(el.value));
+		}
+	}
+	return parts.join("&");
+}
+
+function cell(row, text) {
+	var td = row.insertCell(-1);
+	td.textContent = text || "";
+	return td;
+}
+
+function load() {
+	var q = query();
+	document.getElementById("export").href = "/export.csv?" + q;
+	var xhr = new XMLHttpRequest();
+	xhr.open("GET", "/api/items?" + q);
+	xhr.onload = function() {
+		var items = JSON.parse(xhr.responseText);
+		var tbody = document.getElementById("items");
+		tbody.innerHTML = "";
+		document.getElementById("count").textContent = "Найдено объявлений: " + items.length;
+		items.forEach(function(item) {
+			var row = tbody.insertRow(-1);
+			var link = document.createElement("a");
+			link.href = item.url;
+			link.target = "_blank";
+			link.textContent = item.header;
+			row.insertCell(-1).appendChild(link);
+			cell(row, item.price).className = "price";
+			cell(row, item.phone);
+			cell(row, item.location);
+			cell(row, item.published ? new Date(item.published).toLocaleString("ru") : item.date);
+		});
+	};
+	xhr.send();
+}
+
+form.onsubmit = function(e) {
+	e.preventDefault();
+	load();
+};
+load();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestItemQueryText(t *testing.T) {
+	item := &Item{
+		header:      "Кресло офисное",
+		description: "Самовывоз от метро Сокол",
+		params:      []string{"Состояние: Б/у"},
+	}
+	for _, c := range []struct {
+		q     string
+		match bool
+	}{
+		{"кресло", true},
+		{"СОКОЛ", true},
+		{"б/у", true},
+		{"диван", false},
+		{"офисное самовывоз", false},
+	} {
+		r, err := http.NewRequest("GET", "/api/items?q="+url.QueryEscape(c.q), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parseItemQuery(r).Match(item); got != c.match {
+			t.Errorf("q=%q: Match = %v, ожидалось %v", c.q, got, c.match)
+		}
+	}
+}
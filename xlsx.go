@@ -0,0 +1,329 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Стили ячеек (индексы в cellXfs из xlsx_styles)
+const (
+	xlsx_style_default = iota
+	xlsx_style_header
+	xlsx_style_text
+	xlsx_style_number
+	xlsx_style_date
+	xlsx_style_link
+)
+
+// Ширина и тип ячеек колонки в xlsx. Колонки берутся из того же списка,
+// что и для csv (--columns), по умолчанию - xlsx_default_columns.
+type xlsxColumn struct {
+	width int
+	write func(x *XLSXWriter, col, row int, item *Item)
+}
+
+var xlsx_default_columns = []string{"id", "header", "price", "phone", "location", "date", "url"}
+
+func xlsxText(x *XLSXWriter, col, row int, value string) {
+	x.stringCell(col, row, value, xlsx_style_text)
+}
+
+func xlsxDate(x *XLSXWriter, col, row int, t time.Time, fallback string) {
+	if !t.IsZero() {
+		x.numberCell(col, row, excelDate(t), xlsx_style_date)
+	} else {
+		x.stringCell(col, row, fallback, xlsx_style_default)
+	}
+}
+
+var xlsx_columns = map[string]xlsxColumn{
+	"id":     {12, func(x *XLSXWriter, col, row int, item *Item) { xlsxText(x, col, row, item.id) }},
+	"header": {50, nil},
+	"price": {12, func(x *XLSXWriter, col, row int, item *Item) {
+		if price, ok := parsePrice(item.price); ok {
+			x.numberCell(col, row, fmt.Sprintf("%d", price), xlsx_style_number)
+		} else {
+			x.stringCell(col, row, item.price, xlsx_style_default)
+		}
+	}},
+	"phone":       {18, func(x *XLSXWriter, col, row int, item *Item) { xlsxText(x, col, row, item.phone) }},
+	"location":    {40, nil},
+	"description": {60, nil},
+	"date": {17, func(x *XLSXWriter, col, row int, item *Item) {
+		xlsxDate(x, col, row, item.published, item.date)
+	}},
+	"published": {17, func(x *XLSXWriter, col, row int, item *Item) {
+		xlsxDate(x, col, row, item.published, "")
+	}},
+	"seen": {17, func(x *XLSXWriter, col, row int, item *Item) {
+		xlsxDate(x, col, row, item.seen, "")
+	}},
+	"url": {60, func(x *XLSXWriter, col, row int, item *Item) {
+		x.stringCell(col, row, item.url, xlsx_style_link)
+		if item.url != "" {
+			x.links = append(x.links, xlsxLink{columnName(col) + fmt.Sprint(row), item.url})
+		}
+	}},
+	"params":      {60, nil},
+	"photos":      {60, nil},
+	"photo_paths": {60, nil},
+	"cluster":     {12, func(x *XLSXWriter, col, row int, item *Item) { xlsxText(x, col, row, item.cluster) }},
+}
+
+// Кликабельная ссылка в ячейке ref
+type xlsxLink struct {
+	ref string
+	url string
+}
+
+// Записывает объявления в xlsx файл с типизированными колонками:
+// телефон - текстом, цена - числом, дата - датой, ссылки - кликабельными.
+type XLSXWriter struct {
+	path    string
+	columns []*column
+	rows    bytes.Buffer
+	links   []xlsxLink
+	count   int
+}
+
+func NewXLSXWriter(path string) (*XLSXWriter, error) {
+	// Проверяем, что файл можно создать, до начала парсинга
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	cols, err := xlsxColumns()
+	if err != nil {
+		return nil, err
+	}
+	x := &XLSXWriter{path: path, columns: cols}
+	x.rows.WriteString(`<row r="1">`)
+	for i, col := range cols {
+		title := col.ru
+		if csv_format.header == "en" {
+			title = col.en
+		}
+		x.stringCell(i, 1, title, xlsx_style_header)
+	}
+	x.rows.WriteString(`</row>`)
+	return x, nil
+}
+
+// Колонки xlsx: из --columns или xlsx_default_columns
+// и, если включены, фото, кластер и регион
+func xlsxColumns() ([]*column, error) {
+	if len(csv_format.columns) > 0 {
+		return parseColumns(csv_format.columns)
+	}
+	names := xlsx_default_columns
+	if photos_dir != "" {
+		names = append(names[:len(names):len(names)], "photo_paths")
+	}
+	if dedupe {
+		names = append(names[:len(names):len(names)], "cluster")
+	}
+	if multi_region {
+		names = append(names[:len(names):len(names)], "region")
+	}
+	return parseColumns(names)
+}
+
+func (x *XLSXWriter) Write(item *Item) error {
+	x.count++
+	row := x.count + 1
+
+	fmt.Fprintf(&x.rows, `<row r="%d">`, row)
+	for i, col := range x.columns {
+		if w := xlsx_columns[col.name].write; w != nil {
+			w(x, i, row, item)
+		} else {
+			x.stringCell(i, row, col.get(item), xlsx_style_default)
+		}
+	}
+	x.rows.WriteString(`</row>`)
+	return nil
+}
+
+func (x *XLSXWriter) Close() error {
+	f, err := os.Create(x.path)
+	if err != nil {
+		return err
+	}
+
+	z := zip.NewWriter(f)
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", xlsx_content_types},
+		{"_rels/.rels", xlsx_rels},
+		{"xl/workbook.xml", x.workbook()},
+		{"xl/_rels/workbook.xml.rels", xlsx_workbook_rels},
+		{"xl/styles.xml", xlsx_styles},
+		{"xl/worksheets/sheet1.xml", x.sheet()},
+		{"xl/worksheets/_rels/sheet1.xml.rels", x.sheetRels()},
+	}
+	for _, file := range files {
+		w, err := z.Create(file.name)
+		if err == nil {
+			_, err = io.WriteString(w, file.body)
+		}
+		if err != nil {
+			z.Close()
+			f.Close()
+			return err
+		}
+	}
+
+	err = z.Close()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Ширина колонки в символах
+func xlsxWidth(col *column) int {
+	if c, ok := xlsx_columns[col.name]; ok {
+		return c.width
+	}
+	return 20
+}
+
+// Последняя колонка и строка таблицы (для автофильтра)
+func (x *XLSXWriter) lastCell() (string, int) {
+	return columnName(len(x.columns) - 1), x.count + 1
+}
+
+func (x *XLSXWriter) workbook() string {
+	col, row := x.lastCell()
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Объявления" sheetId="1" r:id="rId1"/></sheets>
+<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">'Объявления'!$A$1:$%s$%d</definedName></definedNames>
+</workbook>`, col, row)
+}
+
+func (x *XLSXWriter) sheet() string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	b.WriteString(`<cols>`)
+	for i, col := range x.columns {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, xlsxWidth(col))
+	}
+	b.WriteString(`</cols>`)
+
+	b.WriteString(`<sheetData>`)
+	b.Write(x.rows.Bytes())
+	b.WriteString(`</sheetData>`)
+
+	col, row := x.lastCell()
+	fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, col, row)
+
+	if len(x.links) > 0 {
+		b.WriteString(`<hyperlinks>`)
+		for i, link := range x.links {
+			fmt.Fprintf(&b, `<hyperlink ref="%s" r:id="rId%d"/>`, link.ref, i+1)
+		}
+		b.WriteString(`</hyperlinks>`)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func (x *XLSXWriter) sheetRels() string {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, link := range x.links {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`,
+			i+1, xmlEscape(link.url))
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (x *XLSXWriter) stringCell(col, row int, value string, style int) {
+	fmt.Fprintf(&x.rows, `<c r="%s%d" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+		columnName(col), row, style, xmlEscape(value))
+}
+
+func (x *XLSXWriter) numberCell(col, row int, value string, style int) {
+	fmt.Fprintf(&x.rows, `<c r="%s%d" s="%d"><v>%s</v></c>`, columnName(col), row, style, value)
+}
+
+// Буквенное имя колонки: 0 -> A, 25 -> Z, 26 -> AA
+func columnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+// Дата в формате Excel: количество дней с 30.12.1899
+func excelDate(t time.Time) string {
+	_, offset := t.Zone()
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	days := float64(t.Unix()+int64(offset)-epoch.Unix()) / 86400
+	return fmt.Sprintf("%.6f", days)
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsx_content_types = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsx_rels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsx_workbook_rels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+const xlsx_styles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="dd.mm.yyyy hh:mm"/></numFmts>
+<fonts count="3">
+<font><sz val="11"/><name val="Calibri"/></font>
+<font><b/><sz val="11"/><name val="Calibri"/></font>
+<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font>
+</fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="49" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="3" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Читает файл из xlsx архива
+func readXLSXPart(t *testing.T, path, name string) string {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	t.Fatalf("в %s нет %s", path, name)
+	return ""
+}
+
+func TestXLSXColumns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selavito")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(f CSVFormat) { csv_format = f }(csv_format)
+
+	csv_format.columns = []string{"url", "phone", "price", "published"}
+	csv_format.header = "en"
+	path := filepath.Join(dir, "test.xlsx")
+	x, err := NewXLSXWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &Item{
+		url:       "https://m.avito.ru/moskva/mebel/kreslo_1",
+		phone:     "89001112233",
+		price:     "5 000 руб.",
+		published: time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC),
+	}
+	if err := x.Write(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := readXLSXPart(t, path, "xl/worksheets/sheet1.xml")
+	for _, want := range []string{
+		`<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">URL</t>`,
+		`<c r="D1" s="1" t="inlineStr"><is><t xml:space="preserve">Published</t>`,
+		`<c r="A2" s="5" t="inlineStr"><is><t xml:space="preserve">` + item.url + `</t>`,
+		`<c r="B2" s="2" t="inlineStr"><is><t xml:space="preserve">89001112233</t>`,
+		`<c r="C2" s="3"><v>5000</v></c>`,
+		`<c r="D2" s="4"><v>46314.520833</v></c>`,
+		`<autoFilter ref="A1:D2"/>`,
+		`<hyperlink ref="A2" r:id="rId1"/>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("в листе нет %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="E`) {
+		t.Error("в листе лишние колонки")
+	}
+	if rels := readXLSXPart(t, path, "xl/worksheets/_rels/sheet1.xml.rels"); !strings.Contains(rels, `Target="`+item.url+`"`) {
+		t.Errorf("нет ссылки в %s", rels)
+	}
+}